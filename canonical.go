@@ -0,0 +1,58 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Canonicalize re-serializes the JSON API document raw in a canonical form,
+// with the keys of every object sorted and the "included" resources ordered by
+// type and id, so that semantically identical documents compare equal byte
+// for byte, e.g. in contract tests. Numbers are kept as written.
+func Canonicalize(raw []byte) ([]byte, error) {
+	doc, err := decodeNumbers(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if top, ok := doc.(map[string]interface{}); ok {
+		if included, ok := top["included"].([]interface{}); ok {
+			sort.SliceStable(included, func(i, j int) bool {
+				return canonicalKey(included[i]) < canonicalKey(included[j])
+			})
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+// sortKeys re-serializes the JSON raw with the keys of every object sorted,
+// keeping arrays in order and numbers as written.
+func sortKeys(raw []byte) ([]byte, error) {
+	doc, err := decodeNumbers(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// decodeNumbers decodes the JSON raw into generic values, with numbers as
+// json.Number so that they are written back as they were.
+func decodeNumbers(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// canonicalKey returns the type and id of a decoded resource object.
+func canonicalKey(v interface{}) string {
+	resource, _ := v.(map[string]interface{})
+	return fmt.Sprintf("%v\x00%v", resource["type"], resource["id"])
+}
@@ -0,0 +1,44 @@
+package jsonapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestCanonicalize(t *testing.T) {
+	a := `{
+		"meta": {"total": 2, "generated": {"by": "a", "at": "now"}},
+		"data": [{"id": "1", "type": "posts", "attributes": {"title": "Hi", "body": "There"}}],
+		"included": [
+			{"type": "people", "id": "9", "attributes": {"name": "Ann"}},
+			{"type": "comments", "id": "5", "attributes": {"score": 1.50}}
+		]
+	}`
+	b := `{"included":[{"attributes":{"score":1.50},"id":"5","type":"comments"},{"id":"9","attributes":{"name":"Ann"},"type":"people"}],
+		"data":[{"attributes":{"body":"There","title":"Hi"},"type":"posts","id":"1"}],
+		"meta":{"generated":{"at":"now","by":"a"},"total":2}}`
+
+	canonicalA, err := jsonapi.Canonicalize([]byte(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonicalB, err := jsonapi.Canonicalize([]byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, string(canonicalA), string(canonicalB))
+	assert.Equal(t,
+		`{"data":[{"attributes":{"body":"There","title":"Hi"},"id":"1","type":"posts"}],`+
+			`"included":[{"attributes":{"score":1.50},"id":"5","type":"comments"},{"attributes":{"name":"Ann"},"id":"9","type":"people"}],`+
+			`"meta":{"generated":{"at":"now","by":"a"},"total":2}}`,
+		string(canonicalA))
+}
+
+func TestCanonicalize_Invalid(t *testing.T) {
+	_, err := jsonapi.Canonicalize([]byte(`{"data":`))
+	assert.Error(t, err)
+}
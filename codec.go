@@ -0,0 +1,66 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+)
+
+// Codec encodes and decodes JSON, so that the encoding backend can be
+// swapped, e.g. for jsoniter or go-json in performance-sensitive services.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// DefaultCodec is the Codec used by the marshal and unmarshal entry points of
+// this package to encode and decode documents; it defaults to encoding/json.
+//
+// Note that encoding/json writes map keys sorted, which keeps the attributes
+// and meta of documents deterministic; a replacement should be configured to
+// do the same, as jsoniter.ConfigCompatibleWithStandardLibrary is.
+var DefaultCodec Codec = stdCodec{}
+
+type stdCodec struct{}
+
+func (stdCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// encode writes v to w with DefaultCodec, terminated by a newline as
+// json.Encoder does.
+func encode(w io.Writer, v interface{}) error {
+	data, err := DefaultCodec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// encodeSorted writes v to w as encode does, with the keys of every object
+// sorted whatever DefaultCodec writes.
+func encodeSorted(w io.Writer, v interface{}) error {
+	data, err := DefaultCodec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if data, err = sortKeys(data); err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// decode reads all of in and decodes it into v with DefaultCodec.
+func decode(in io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	return DefaultCodec.Unmarshal(data, v)
+}
@@ -0,0 +1,171 @@
+package jsonapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+type countingCodec struct {
+	marshals, unmarshals int
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshals++
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals++
+	return json.Unmarshal(data, v)
+}
+
+func TestDefaultCodec(t *testing.T) {
+	defer func(codec jsonapi.Codec) { jsonapi.DefaultCodec = codec }(jsonapi.DefaultCodec)
+	codec := new(countingCodec)
+	jsonapi.DefaultCodec = codec
+
+	out := bytes.NewBuffer(nil)
+	assert.NoError(t, jsonapi.MarshalPayload(out, testBlog()))
+	payloadMarshals := codec.marshals
+	assert.Greater(t, payloadMarshals, 1, "resource objects are encoded with the codec too")
+	assert.NoError(t, jsonapi.MarshalErrors(bytes.NewBuffer(nil), []*jsonapi.ErrorObject{{Title: "Bad"}}))
+	assert.Equal(t, payloadMarshals+1, codec.marshals)
+	assert.Equal(t, byte('\n'), out.Bytes()[out.Len()-1])
+
+	blog := new(Blog)
+	assert.NoError(t, jsonapi.UnmarshalPayload(bytes.NewReader(out.Bytes()), blog))
+	assert.Equal(t, testBlog().Title, blog.Title)
+	payloadUnmarshals := codec.unmarshals
+	assert.Greater(t, payloadUnmarshals, 1, "resource objects are decoded with the codec too")
+
+	many := bytes.NewBuffer(nil)
+	assert.NoError(t, jsonapi.MarshalPayload(many, []*Blog{testBlog()}))
+	_, err := jsonapi.UnmarshalManyPayload(many, reflect.TypeOf(new(Blog)))
+	assert.NoError(t, err)
+	assert.Greater(t, codec.unmarshals, payloadUnmarshals)
+}
+
+func TestDefaultCodec_Links(t *testing.T) {
+	defer func(codec jsonapi.Codec) { jsonapi.DefaultCodec = codec }(jsonapi.DefaultCodec)
+	codec := new(countingCodec)
+	jsonapi.DefaultCodec = codec
+
+	data, err := json.Marshal(jsonapi.Links{"self": "/posts/1", "related": jsonapi.Link{Href: "/posts/1/author"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, codec.marshals)
+
+	links := new(jsonapi.Links)
+	assert.NoError(t, json.Unmarshal(data, links))
+	assert.Greater(t, codec.unmarshals, 1, "the links object and its link object are decoded with the codec")
+	assert.Equal(t, &jsonapi.Links{"self": "/posts/1", "related": jsonapi.Link{Href: "/posts/1/author"}}, links)
+}
+
+// reversingCodec writes the members of every object in reverse order, as a
+// codec that does not sort map keys might.
+type reversingCodec struct{}
+
+func (reversingCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	writeReversed(buf, doc)
+	return buf.Bytes(), nil
+}
+
+func (reversingCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func writeReversed(buf *bytes.Buffer, v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(k)
+			buf.Write(key)
+			buf.WriteByte(':')
+			writeReversed(buf, value[k])
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, element := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeReversed(buf, element)
+		}
+		buf.WriteByte(']')
+	default:
+		scalar, _ := json.Marshal(value)
+		buf.Write(scalar)
+	}
+}
+
+// keysSorted reports whether the members of every object of the JSON raw are
+// in sorted order.
+func keysSorted(raw []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+
+	var sorted func() bool
+	sorted = func() bool {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+
+		switch token {
+		case json.Delim('{'):
+			last := ""
+			for first := true; decoder.More(); first = false {
+				key, err := decoder.Token()
+				if err != nil || (!first && key.(string) < last) {
+					return false
+				}
+				last = key.(string)
+				if !sorted() {
+					return false
+				}
+			}
+		case json.Delim('['):
+			for decoder.More() {
+				if !sorted() {
+					return false
+				}
+			}
+		default:
+			return true
+		}
+
+		_, err = decoder.Token()
+		return err == nil
+	}
+
+	return sorted()
+}
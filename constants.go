@@ -34,6 +34,9 @@ const (
 	// KeyNextPage is the key to the links object whose value contains a link to
 	// the next page of data
 	KeyNextPage = "next"
+	// KeySelfPage is the key to the links object whose value contains a link to
+	// the current page of data
+	KeySelfPage = "self"
 
 	// QueryParamPageNumber is a JSON API query parameter used in a page based
 	// pagination strategy in conjunction with QueryParamPageSize
@@ -52,4 +55,49 @@ const (
 	// QueryParamPageCursor is a JSON API query parameter used with a cursor-based
 	// strategy
 	QueryParamPageCursor = "page[cursor]"
+
+	// QueryParamPage is a scalar query parameter, outside of the JSON API page
+	// family, used in a page based strategy in conjunction with
+	// QueryParamPerPage
+	QueryParamPage = "page"
+	// QueryParamPerPage is a scalar query parameter, outside of the JSON API
+	// page family, used in a page based strategy in conjunction with
+	// QueryParamPage
+	QueryParamPerPage = "per_page"
+	// QueryParamSignature is a query parameter holding the signature of the
+	// page parameters of a pagination link, see VerifyPageSignature
+	QueryParamSignature = "sig"
+
+	// Sparse Fieldset Constants
+	//
+	// http://jsonapi.org/format/#fetching-sparse-fieldsets
+
+	// QueryParamFields is the family of JSON API query parameters, in the form
+	// fields[TYPE], used to request a sparse fieldset for a resource type
+	QueryParamFields = "fields"
+
+	// Inclusion Constants
+	//
+	// http://jsonapi.org/format/#fetching-includes
+
+	// QueryParamInclude is the JSON API query parameter holding the
+	// comma-separated relationship paths, e.g. "comments.author", of the
+	// related resources to include
+	QueryParamInclude = "include"
+
+	// Sorting Constants
+	//
+	// http://jsonapi.org/format/#fetching-sorting
+
+	// QueryParamSort is the JSON API query parameter holding the
+	// comma-separated sort fields, each descending when prefixed with "-"
+	QueryParamSort = "sort"
+
+	// Filtering Constants
+	//
+	// http://jsonapi.org/format/#fetching-filtering
+
+	// QueryParamFilter is the family of query parameters, in the form
+	// filter[NAME] or filter[NAME][NESTED], used to filter a collection
+	QueryParamFilter = "filter"
 )
@@ -0,0 +1,39 @@
+package jsonapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned by DecodeCursor when the cursor was not
+// produced by EncodeCursor.
+var ErrInvalidCursor = errors.New("cursor is not a valid encoded cursor")
+
+// EncodeCursor returns the value v of a cursor, e.g. the timestamp and id of
+// the last resource of a page, serialized as JSON and base64-encoded, for use
+// as an opaque page[cursor] parameter. Opaque means clients should not rely
+// on its contents, not that they cannot read them: anyone can base64-decode
+// a cursor. A cursor is only tamper-evident when the links carrying it are
+// signed, e.g. with the SigningSecret of OffsetPagination and checked with
+// VerifyPageSignature.
+func EncodeCursor(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes cursor, as returned by EncodeCursor, into target, a
+// pointer to a value of the type that was encoded.
+func DecodeCursor(cursor string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package jsonapi_test
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+type timeCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+func TestEncodeCursor(t *testing.T) {
+	in := timeCursor{CreatedAt: time.Date(2016, 8, 17, 8, 27, 12, 500, time.UTC), ID: "42"}
+
+	cursor, err := jsonapi.EncodeCursor(in)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, url.QueryEscape(cursor), "cursors should need no escaping in a query")
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	assert.NoError(t, err)
+	assert.Contains(t, string(decoded), `"id":"42"`, "cursors are opaque but readable")
+
+	var out timeCursor
+	assert.NoError(t, jsonapi.DecodeCursor(cursor, &out))
+	assert.True(t, in.CreatedAt.Equal(out.CreatedAt))
+	assert.Equal(t, in.ID, out.ID)
+
+	assert.Equal(t, jsonapi.ErrInvalidCursor, jsonapi.DecodeCursor("not a cursor!", &out))
+	assert.Equal(t, jsonapi.ErrInvalidCursor, jsonapi.DecodeCursor("bm90IGpzb24", &out))
+}
@@ -1,9 +1,11 @@
 package jsonapi
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 )
 
 // MarshalErrors writes a JSON API response using the given `[]error`.
@@ -11,13 +13,66 @@ import (
 // For more information on JSON API error payloads, see the spec here:
 // http://jsonapi.org/format/#document-top-level
 // and here: http://jsonapi.org/format/#error-objects.
+//
+// With the default codec the members of each object are written in sorted
+// order; MarshalErrorsWithOptions with SortKeys guarantees it for any codec.
 func MarshalErrors(w io.Writer, errorObjects []*ErrorObject) error {
-	return json.NewEncoder(w).Encode(&ErrorsPayload{Errors: errorObjects})
+	return encode(w, &ErrorsPayload{Errors: errorObjects})
+}
+
+// MarshalErrorsWithMeta does the same as MarshalErrors, adding meta to the
+// top level of the errors document, e.g. a request id spanning all errors.
+func MarshalErrorsWithMeta(w io.Writer, errorObjects []*ErrorObject, meta *Meta) error {
+	return encode(w, &ErrorsPayload{Errors: errorObjects, Meta: meta})
+}
+
+// MarshalErrorsWithLinks does the same as MarshalErrors, adding links to the
+// top level of the errors document, e.g. an "about" link to documentation
+// spanning all errors. Links of a single error go in its ErrorObject.Links.
+func MarshalErrorsWithLinks(w io.Writer, errorObjects []*ErrorObject, links *Links) error {
+	if links != nil {
+		if err := links.validate(); err != nil {
+			return err
+		}
+	}
+	return encode(w, &ErrorsPayload{Errors: errorObjects, Links: links})
+}
+
+// ErrorsOptions configures MarshalErrorsWithOptions.
+type ErrorsOptions struct {
+	// Links and Meta are added to the top level of the errors document, as by
+	// MarshalErrorsWithLinks and MarshalErrorsWithMeta.
+	Links *Links
+	Meta  *Meta
+
+	// SortKeys writes the members of every object in the document, including
+	// the meta and links of each error, in sorted order whatever the
+	// DefaultCodec, so that the bytes are stable for golden tests, as
+	// MarshalOptions.SortKeys does for documents. The errors keep their order.
+	SortKeys bool
+}
+
+// MarshalErrorsWithOptions does the same as MarshalErrors, configured by
+// opts.
+func MarshalErrorsWithOptions(w io.Writer, errorObjects []*ErrorObject, opts ErrorsOptions) error {
+	if opts.Links != nil {
+		if err := opts.Links.validate(); err != nil {
+			return err
+		}
+	}
+
+	payload := &ErrorsPayload{Errors: errorObjects, Links: opts.Links, Meta: opts.Meta}
+	if opts.SortKeys {
+		return encodeSorted(w, payload)
+	}
+	return encode(w, payload)
 }
 
 // ErrorsPayload is a serializer struct for representing a valid JSON API errors payload.
 type ErrorsPayload struct {
 	Errors []*ErrorObject `json:"errors"`
+	Links  *Links         `json:"links,omitempty"`
+	Meta   *Meta          `json:"meta,omitempty"`
 }
 
 // ErrorObject is an `Error` implementation as well as an implementation of the JSON API error object.
@@ -59,11 +114,108 @@ func (e *ErrorObject) Error() string {
 
 // ErrorSource is an object used to identify the source of the error.
 type ErrorSource struct {
-	Pointer string `json:"pointer,omitempty"`
+	Pointer   string `json:"pointer,omitempty"`
 	Parameter string `json:"parameter,omitempty"`
+	// Header is the name of the request header that caused the error (JSON
+	// API 1.1).
+	Header string `json:"header,omitempty"`
+}
+
+// NewHeaderError returns an ErrorObject whose source is the request header
+// named header, e.g. for an unsupported Accept or Content-Type.
+func NewHeaderError(header, title, detail string) *ErrorObject {
+	return &ErrorObject{
+		Title:  title,
+		Detail: detail,
+		Source: &ErrorSource{Header: header},
+	}
 }
 
 // ErrorLink is an object providing access to the `about` detail of an error.
 type ErrorLink struct {
 	About string `json:"about,omitempty"`
-}
\ No newline at end of file
+}
+
+// StatusText returns the title of an error with the HTTP status code, used by
+// FillStatusTitles and ErrorsFromError. It defaults to http.StatusText, and
+// can be replaced, e.g. to localize titles.
+var StatusText = http.StatusText
+
+// FillStatusTitles sets the Title of each of errs that has none from its
+// Status, e.g. "Not Found" for "404", so handlers need not set both.
+func FillStatusTitles(errs ...*ErrorObject) {
+	for _, e := range errs {
+		if e == nil || e.Title != "" {
+			continue
+		}
+		status, err := strconv.Atoi(e.Status)
+		if err != nil {
+			continue
+		}
+		e.Title = StatusText(status)
+	}
+}
+
+// StatusCoder is implemented by errors that know the HTTP status code
+// applicable to them, used as the status of their ErrorObject by
+// ErrorsFromError.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Titler is implemented by errors that provide the title of their
+// ErrorObject to ErrorsFromError.
+type Titler interface {
+	Title() string
+}
+
+// ErrorsFromError converts the Go error err into error objects for
+// MarshalErrors. An *ErrorObject in the chain of err is used as is. An error
+// joining several errors, with an Unwrap() []error or WrappedErrors() []error
+// method as multierror packages have, gives an error object per error.
+// Otherwise the error object has err's message as its detail, the status of
+// a StatusCoder in the chain of err, and the title of a Titler in the chain,
+// defaulting to the text of the status, or "Error" without one.
+func ErrorsFromError(err error) []*ErrorObject {
+	if err == nil {
+		return nil
+	}
+
+	var errs []error
+	switch multi := err.(type) {
+	case interface{ Unwrap() []error }:
+		errs = multi.Unwrap()
+	case interface{ WrappedErrors() []error }:
+		errs = multi.WrappedErrors()
+	}
+	if errs != nil {
+		errorObjects := make([]*ErrorObject, 0, len(errs))
+		for _, e := range errs {
+			errorObjects = append(errorObjects, ErrorsFromError(e)...)
+		}
+		return errorObjects
+	}
+
+	var errorObject *ErrorObject
+	if errors.As(err, &errorObject) {
+		return []*ErrorObject{errorObject}
+	}
+
+	errorObject = &ErrorObject{Title: "Error", Detail: err.Error()}
+
+	var statusCoder StatusCoder
+	if errors.As(err, &statusCoder) {
+		status := statusCoder.StatusCode()
+		errorObject.Status = strconv.Itoa(status)
+		if text := StatusText(status); text != "" {
+			errorObject.Title = text
+		}
+	}
+
+	var titler Titler
+	if errors.As(err, &titler) {
+		errorObject.Title = titler.Title()
+	}
+
+	return []*ErrorObject{errorObject}
+}
@@ -100,6 +100,20 @@ func TestMarshalErrorsWritesTheExpectedPayload(t *testing.T) {
 				}},
 			},
 		},
+		"TestHeaderSourceFieldIsSerializedProperly": {
+			In: []*jsonapi.ErrorObject{
+				jsonapi.NewHeaderError("Accept", "Not acceptable.", "Unsupported media type parameter"),
+			},
+			Out: map[string]interface{}{
+				"errors": []interface{}{map[string]interface{}{
+					"title":  "Not acceptable.",
+					"detail": "Unsupported media type parameter",
+					"source": map[string]interface{}{
+						"header": "Accept",
+					},
+				}},
+			},
+		},
 	}
 
 	for name, test := range marshalErrorsTableTasts {
@@ -116,3 +130,206 @@ func TestMarshalErrorsWritesTheExpectedPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshalErrorsWithMeta(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	errs := []*jsonapi.ErrorObject{{Title: "Test title.", Status: "400"}}
+
+	if err := jsonapi.MarshalErrorsWithMeta(buffer, errs, &jsonapi.Meta{"request_id": "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := map[string]interface{}{}
+	if err := json.Unmarshal(buffer.Bytes(), &output); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"errors": []interface{}{map[string]interface{}{"title": "Test title.", "status": "400"}},
+		"meta":   map[string]interface{}{"request_id": "abc123"},
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Fatalf("Expected: \n%#v \nto equal: \n%#v", output, expected)
+	}
+
+	buffer.Reset()
+	if err := jsonapi.MarshalErrors(buffer, errs); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buffer.Bytes(), []byte(`"meta"`)) {
+		t.Fatalf("Expected no meta member, got %s", buffer.String())
+	}
+}
+
+func TestMarshalErrorsWithLinks(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	errs := []*jsonapi.ErrorObject{{
+		Title:  "Test title.",
+		Status: "400",
+		Links:  &jsonapi.ErrorLink{About: "https://example.com/errors/bad-title"},
+	}}
+
+	links := &jsonapi.Links{"about": "https://example.com/errors"}
+	if err := jsonapi.MarshalErrorsWithLinks(buffer, errs, links); err != nil {
+		t.Fatal(err)
+	}
+
+	output := map[string]interface{}{}
+	if err := json.Unmarshal(buffer.Bytes(), &output); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"errors": []interface{}{map[string]interface{}{
+			"title":  "Test title.",
+			"status": "400",
+			"links":  map[string]interface{}{"about": "https://example.com/errors/bad-title"},
+		}},
+		"links": map[string]interface{}{"about": "https://example.com/errors"},
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Fatalf("Expected: \n%#v \nto equal: \n%#v", output, expected)
+	}
+
+	if err := jsonapi.MarshalErrorsWithLinks(bytes.NewBuffer(nil), errs, &jsonapi.Links{"about": 42}); err == nil {
+		t.Fatal("Expected an error for an invalid links member")
+	}
+}
+
+type notFoundError struct{ id string }
+
+func (e notFoundError) Error() string   { return fmt.Sprintf("blog %s does not exist", e.id) }
+func (e notFoundError) StatusCode() int { return 404 }
+
+type titledError struct{}
+
+func (titledError) Error() string   { return "the title is too long" }
+func (titledError) StatusCode() int { return 422 }
+func (titledError) Title() string   { return "Invalid Attribute" }
+
+type multiError []error
+
+func (m multiError) Error() string          { return "multiple errors" }
+func (m multiError) WrappedErrors() []error { return m }
+
+func TestErrorsFromError(t *testing.T) {
+	var tests = map[string]struct {
+		err      error
+		expected []*jsonapi.ErrorObject
+	}{
+		"nil": {
+			err: nil,
+		},
+		"plain error": {
+			err:      fmt.Errorf("something broke"),
+			expected: []*jsonapi.ErrorObject{{Title: "Error", Detail: "something broke"}},
+		},
+		"status coder": {
+			err:      fmt.Errorf("loading: %w", notFoundError{id: "1"}),
+			expected: []*jsonapi.ErrorObject{{Status: "404", Title: "Not Found", Detail: "loading: blog 1 does not exist"}},
+		},
+		"titler": {
+			err:      titledError{},
+			expected: []*jsonapi.ErrorObject{{Status: "422", Title: "Invalid Attribute", Detail: "the title is too long"}},
+		},
+		"error object": {
+			err:      fmt.Errorf("wrapped: %w", &jsonapi.ErrorObject{Title: "Conflict", Status: "409"}),
+			expected: []*jsonapi.ErrorObject{{Title: "Conflict", Status: "409"}},
+		},
+		"multierror": {
+			err: multiError{notFoundError{id: "2"}, titledError{}},
+			expected: []*jsonapi.ErrorObject{
+				{Status: "404", Title: "Not Found", Detail: "blog 2 does not exist"},
+				{Status: "422", Title: "Invalid Attribute", Detail: "the title is too long"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := jsonapi.ErrorsFromError(test.err)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("Expected %#v, got %#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFillStatusTitles(t *testing.T) {
+	errs := []*jsonapi.ErrorObject{
+		{Status: "404"},
+		{Status: "422", Detail: "The title is too long"},
+		{Status: "409", Title: "Version Mismatch"},
+		{Status: "bogus"},
+	}
+	jsonapi.FillStatusTitles(errs...)
+
+	expected := []*jsonapi.ErrorObject{
+		{Status: "404", Title: "Not Found"},
+		{Status: "422", Title: "Unprocessable Entity", Detail: "The title is too long"},
+		{Status: "409", Title: "Version Mismatch"},
+		{Status: "bogus"},
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Fatalf("Expected %#v, got %#v", expected, errs)
+	}
+
+	defer func(text func(int) string) { jsonapi.StatusText = text }(jsonapi.StatusText)
+	jsonapi.StatusText = func(code int) string { return fmt.Sprintf("Status %d", code) }
+
+	e := &jsonapi.ErrorObject{Status: "404"}
+	jsonapi.FillStatusTitles(e)
+	if e.Title != "Status 404" {
+		t.Fatalf("Expected the overridden title, got %q", e.Title)
+	}
+}
+
+type unsortedCodec struct{}
+
+func (unsortedCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(`{"meta":{"z":1,"a":2},"errors":[{"title":"Second","status":"409"},{"status":"400","title":"First"}]}`), nil
+}
+
+func (unsortedCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestMarshalErrorsWithOptions_SortKeys(t *testing.T) {
+	meta := map[string]interface{}{"retry_after": 30, "field": "title", "allowed": []string{"a", "b"}}
+	errs := []*jsonapi.ErrorObject{
+		{Title: "Second", Status: "409", Meta: &meta},
+		{Title: "First", Status: "400", Source: &jsonapi.ErrorSource{Pointer: "/data/attributes/title"}},
+	}
+	opts := jsonapi.ErrorsOptions{
+		Meta:     &jsonapi.Meta{"request_id": "abc", "api_version": "2"},
+		Links:    &jsonapi.Links{"about": "https://example.com/errors"},
+		SortKeys: true,
+	}
+
+	expected := `{"errors":[` +
+		`{"meta":{"allowed":["a","b"],"field":"title","retry_after":30},"status":"409","title":"Second"},` +
+		`{"source":{"pointer":"/data/attributes/title"},"status":"400","title":"First"}],` +
+		`"links":{"about":"https://example.com/errors"},"meta":{"api_version":"2","request_id":"abc"}}` + "\n"
+
+	for i := 0; i < 3; i++ {
+		buffer := bytes.NewBuffer(nil)
+		if err := jsonapi.MarshalErrorsWithOptions(buffer, errs, opts); err != nil {
+			t.Fatal(err)
+		}
+		if buffer.String() != expected {
+			t.Fatalf("Expected: \n%s \nto equal: \n%s", buffer.String(), expected)
+		}
+	}
+
+	defer func(codec jsonapi.Codec) { jsonapi.DefaultCodec = codec }(jsonapi.DefaultCodec)
+	jsonapi.DefaultCodec = unsortedCodec{}
+
+	buffer := bytes.NewBuffer(nil)
+	if err := jsonapi.MarshalErrorsWithOptions(buffer, errs, jsonapi.ErrorsOptions{SortKeys: true}); err != nil {
+		t.Fatal(err)
+	}
+	expected = `{"errors":[{"status":"409","title":"Second"},{"status":"400","title":"First"}],"meta":{"a":2,"z":1}}` + "\n"
+	if buffer.String() != expected {
+		t.Fatalf("Expected keys sorted whatever the codec, got %s", buffer.String())
+	}
+}
@@ -0,0 +1,124 @@
+package jsonapi
+
+import (
+	"reflect"
+)
+
+// EstimateSize returns the approximate size, in bytes, of p once serialized,
+// based on the lengths of its members, so callers can size a buffer, e.g.
+// with bytes.Buffer.Grow, before marshaling. It does not account for escaping
+// or for the exact formatting of numbers, and it is not exact.
+func EstimateSize(p Payloader) int {
+	size := len(`{"data":}`)
+
+	switch payload := p.(type) {
+	case *OnePayload:
+		size += estimateResource(payload.Data)
+		size += estimateResources(`,"included":`, payload.Included)
+		size += estimateMember(`,"links":`, payload.Links)
+		size += estimateMember(`,"meta":`, payload.Meta)
+	case *ManyPayload:
+		size += estimateResources("", payload.Data)
+		size += estimateResources(`,"included":`, payload.Included)
+		size += estimateMember(`,"links":`, payload.Links)
+		size += estimateMember(`,"meta":`, payload.Meta)
+	}
+
+	return size
+}
+
+func estimateResources(name string, resources []*ResourceObj) int {
+	if name != "" && len(resources) == 0 {
+		return 0
+	}
+
+	size := len(name) + len("[]")
+	for _, n := range resources {
+		size += estimateResource(n) + len(",")
+	}
+	return size
+}
+
+func estimateResource(n *ResourceObj) int {
+	if n == nil {
+		return len("null")
+	}
+
+	size := len(`{"type":"","id":""}`) + len(n.Type) + len(n.ID)
+	if len(n.Attributes) > 0 {
+		size += len(`,"attributes":`) + estimateValue(n.Attributes)
+	}
+	if len(n.Relationships) > 0 {
+		size += len(`,"relationships":`) + estimateValue(n.Relationships)
+	}
+	size += estimateMember(`,"links":`, n.Links)
+	size += estimateMember(`,"meta":`, n.Meta)
+	return size
+}
+
+func estimateMember(name string, v interface{}) int {
+	if isNull(v) {
+		return 0
+	}
+	return len(name) + estimateValue(v)
+}
+
+func estimateValue(v interface{}) int {
+	switch value := v.(type) {
+	case nil:
+		return len("null")
+	case string:
+		return len(value) + len(`""`)
+	case bool:
+		return len("false")
+	case *ResourceObj:
+		return estimateResource(value)
+	case *RelationshipOneNode:
+		return len(`{"data":}`) + estimateResource(value.Data) +
+			estimateMember(`,"links":`, value.Links) + estimateMember(`,"meta":`, value.Meta)
+	case *RelationshipManyNode:
+		return len(`{"data":}`) + estimateResources("", value.Data) +
+			estimateMember(`,"links":`, value.Links) + estimateMember(`,"meta":`, value.Meta)
+	case Link:
+		return len(`{"href":""}`) + len(value.Href) + estimateMember(`,"meta":`, value.Meta)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return len("null")
+		}
+		return estimateValue(rv.Elem().Interface())
+	case reflect.String:
+		return rv.Len() + len(`""`)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return 8
+	case reflect.Map:
+		size := len("{}")
+		iter := rv.MapRange()
+		for iter.Next() {
+			size += len(`"":,`) + len(iter.Key().String()) + estimateValue(iter.Value().Interface())
+		}
+		return size
+	case reflect.Slice, reflect.Array:
+		size := len("[]")
+		for i := 0; i < rv.Len(); i++ {
+			size += estimateValue(rv.Index(i).Interface()) + len(",")
+		}
+		return size
+	case reflect.Struct:
+		size := len("{}")
+		for i := 0; i < rv.NumField(); i++ {
+			if rv.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			size += len(`"":,`) + len(rv.Type().Field(i).Name) + estimateValue(rv.Field(i).Interface())
+		}
+		return size
+	}
+
+	return 16
+}
@@ -0,0 +1,42 @@
+package jsonapi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestEstimateSize(t *testing.T) {
+	var blogs []interface{}
+	for i := 0; i < 20; i++ {
+		blogs = append(blogs, testBlog())
+	}
+
+	for name, models := range map[string]interface{}{
+		"one":  testBlog(),
+		"many": blogs,
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, err := jsonapi.Marshal(models)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := json.Marshal(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			estimate := jsonapi.EstimateSize(p)
+			assert.Greater(t, estimate, len(out)/2)
+			assert.Less(t, estimate, len(out)*2)
+		})
+	}
+}
+
+func TestEstimateSize_Empty(t *testing.T) {
+	assert.Equal(t, len(`{"data":null}`), jsonapi.EstimateSize(&jsonapi.OnePayload{}))
+}
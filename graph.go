@@ -0,0 +1,238 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ResolvedGraph provides ordered access to the included resources of a
+// compound document. Related resources are returned in the order of the
+// relationship linkage, so that consumers can render them in the same
+// deterministic sequence as the document described them.
+type ResolvedGraph struct {
+	data     []*ResourceObj
+	included map[string]*ResourceObj
+}
+
+// NewResolvedGraph builds a ResolvedGraph from a OnePayload or ManyPayload.
+func NewResolvedGraph(p Payloader) *ResolvedGraph {
+	g := &ResolvedGraph{included: make(map[string]*ResourceObj)}
+
+	var included []*ResourceObj
+	switch payload := p.(type) {
+	case *OnePayload:
+		if payload.Data != nil {
+			g.data = []*ResourceObj{payload.Data}
+		}
+		included = payload.Included
+	case *ManyPayload:
+		g.data = payload.Data
+		included = payload.Included
+	}
+
+	for _, n := range included {
+		g.included[resourceKey(n)] = n
+	}
+
+	return g
+}
+
+// Data returns the primary resources of the document.
+func (g *ResolvedGraph) Data() []*ResourceObj {
+	return g.data
+}
+
+// Related returns the resources referenced by the named relationship of
+// node, in linkage order. Resources found in the "included" array are
+// returned in full, otherwise the resource identifier is returned.
+func (g *ResolvedGraph) Related(node *ResourceObj, relation string) []*ResourceObj {
+	if node == nil {
+		return nil
+	}
+
+	linkage := relationshipLinkage(node.Relationships[relation])
+	related := make([]*ResourceObj, 0, len(linkage))
+	for _, n := range linkage {
+		related = append(related, fullNode(n, &g.included))
+	}
+
+	return related
+}
+
+// Path follows a dot separated include path (e.g. "comments.author") from the
+// primary resources and returns the resources reached at the end of the path.
+// Resources are ordered by the order in which they were first reached and
+// each resource is only returned once.
+func (g *ResolvedGraph) Path(path string) []*ResourceObj {
+	nodes := g.data
+
+	for _, relation := range strings.Split(path, ".") {
+		seen := make(map[string]bool)
+		next := []*ResourceObj{}
+
+		for _, node := range nodes {
+			for _, n := range g.Related(node, relation) {
+				key := resourceKey(n)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				next = append(next, n)
+			}
+		}
+
+		nodes = next
+	}
+
+	return nodes
+}
+
+// ResolveOptions configures ResolveRelationships.
+type ResolveOptions struct {
+	// MaxDepth is the number of relationship levels expanded below the
+	// primary resources; relationships beyond it are left as linkage. Zero
+	// means no limit.
+	MaxDepth int
+}
+
+// ResolveRelationships returns copies of the primary resources in which the
+// resource identifiers of each relationship are replaced, recursively, by
+// the included resources they refer to. A resource that is already being
+// expanded higher up the same path is left as linkage, so cyclic graphs
+// terminate even without a MaxDepth.
+func (g *ResolvedGraph) ResolveRelationships(opts ResolveOptions) []*ResourceObj {
+	resolved := make([]*ResourceObj, 0, len(g.data))
+	for _, n := range g.data {
+		resolved = append(resolved, g.resolve(n, 0, opts, map[string]bool{resourceKey(n): true}))
+	}
+	return resolved
+}
+
+func (g *ResolvedGraph) resolve(n *ResourceObj, depth int, opts ResolveOptions, path map[string]bool) *ResourceObj {
+	resolved := *n
+	if n.Relationships == nil {
+		return &resolved
+	}
+
+	expand := opts.MaxDepth == 0 || depth < opts.MaxDepth
+
+	resolved.Relationships = make(map[string]interface{}, len(n.Relationships))
+	for name, relationship := range n.Relationships {
+		if !expand {
+			resolved.Relationships[name] = relationship
+			continue
+		}
+
+		resolveNode := func(identifier *ResourceObj) *ResourceObj {
+			if identifier == nil {
+				return nil
+			}
+			key := resourceKey(identifier)
+			full, ok := g.included[key]
+			if !ok || path[key] {
+				return identifier
+			}
+
+			path[key] = true
+			defer delete(path, key)
+			return g.resolve(full, depth+1, opts, path)
+		}
+
+		switch rel := normalizeRelationship(relationship).(type) {
+		case *RelationshipOneNode:
+			resolved.Relationships[name] = &RelationshipOneNode{
+				Data:  resolveNode(rel.Data),
+				Links: rel.Links,
+				Meta:  rel.Meta,
+			}
+		case *RelationshipManyNode:
+			data := make([]*ResourceObj, 0, len(rel.Data))
+			for _, identifier := range rel.Data {
+				data = append(data, resolveNode(identifier))
+			}
+			resolved.Relationships[name] = &RelationshipManyNode{
+				Data:  data,
+				Links: rel.Links,
+				Meta:  rel.Meta,
+			}
+		default:
+			resolved.Relationships[name] = relationship
+		}
+	}
+
+	return &resolved
+}
+
+// normalizeRelationship returns relationship as a RelationshipOneNode or
+// RelationshipManyNode, according to the cardinality of its linkage, when it
+// is held as decoded generic JSON. Relationships without linkage are returned
+// unchanged.
+func normalizeRelationship(relationship interface{}) interface{} {
+	switch relationship.(type) {
+	case nil, *RelationshipOneNode, *RelationshipManyNode, *RelationshipMetaNode:
+		return relationship
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(relationship); err != nil {
+		return relationship
+	}
+
+	var rel struct {
+		Data  json.RawMessage `json:"data"`
+		Links *Links          `json:"links,omitempty"`
+		Meta  *Meta           `json:"meta,omitempty"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &rel); err != nil || len(rel.Data) == 0 {
+		return relationship
+	}
+
+	var data RelationshipData
+	if err := json.Unmarshal(rel.Data, &data); err != nil {
+		return relationship
+	}
+
+	if data.IsMany() {
+		return &RelationshipManyNode{Data: data.Many(), Links: rel.Links, Meta: rel.Meta}
+	}
+	return &RelationshipOneNode{Data: data.One(), Links: rel.Links, Meta: rel.Meta}
+}
+
+// relationshipLinkage returns the resource identifiers of a relationship
+// whether it is held as a relationship node or as decoded generic JSON.
+func relationshipLinkage(relationship interface{}) []*ResourceObj {
+	switch rel := relationship.(type) {
+	case nil:
+		return nil
+	case *RelationshipOneNode:
+		if rel.Data == nil {
+			return nil
+		}
+		return []*ResourceObj{rel.Data}
+	case *RelationshipManyNode:
+		return rel.Data
+	case *RelationshipMetaNode:
+		return nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	json.NewEncoder(buf).Encode(relationship)
+
+	many := new(RelationshipManyNode)
+	if err := json.Unmarshal(buf.Bytes(), many); err == nil {
+		return many.Data
+	}
+
+	one := new(RelationshipOneNode)
+	if err := json.Unmarshal(buf.Bytes(), one); err == nil && one.Data != nil {
+		return []*ResourceObj{one.Data}
+	}
+
+	return nil
+}
+
+func resourceKey(n *ResourceObj) string {
+	return fmt.Sprintf("%s,%s", n.Type, n.ID)
+}
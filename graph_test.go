@@ -0,0 +1,169 @@
+package jsonapi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+const compoundDocument = `{
+	"data": [{
+		"type": "posts",
+		"id": "1",
+		"relationships": {
+			"comments": {"data": [
+				{"type": "comments", "id": "3"},
+				{"type": "comments", "id": "1"},
+				{"type": "comments", "id": "2"}
+			]}
+		}
+	}, {
+		"type": "posts",
+		"id": "2",
+		"relationships": {
+			"comments": {"data": [
+				{"type": "comments", "id": "2"},
+				{"type": "comments", "id": "4"}
+			]}
+		}
+	}],
+	"included": [
+		{"type": "comments", "id": "1", "attributes": {"body": "one"}, "relationships": {"author": {"data": {"type": "people", "id": "b"}}}},
+		{"type": "comments", "id": "2", "attributes": {"body": "two"}, "relationships": {"author": {"data": {"type": "people", "id": "a"}}}},
+		{"type": "comments", "id": "3", "attributes": {"body": "three"}, "relationships": {"author": {"data": {"type": "people", "id": "c"}}}},
+		{"type": "comments", "id": "4", "attributes": {"body": "four"}, "relationships": {"author": {"data": null}}},
+		{"type": "people", "id": "a", "attributes": {"name": "Ann"}},
+		{"type": "people", "id": "b", "attributes": {"name": "Bob"}},
+		{"type": "people", "id": "c", "attributes": {"name": "Cat"}}
+	]
+}`
+
+func ids(nodes []*jsonapi.ResourceObj) []string {
+	out := []string{}
+	for _, n := range nodes {
+		out = append(out, n.ID)
+	}
+	return out
+}
+
+func TestResolvedGraph_Related(t *testing.T) {
+	payload := new(jsonapi.ManyPayload)
+	if err := json.Unmarshal([]byte(compoundDocument), payload); err != nil {
+		t.Fatal(err)
+	}
+
+	graph := jsonapi.NewResolvedGraph(payload)
+
+	comments := graph.Related(graph.Data()[0], "comments")
+	assert.Equal(t, []string{"3", "1", "2"}, ids(comments))
+	assert.Equal(t, "three", comments[0].Attributes["body"])
+
+	assert.Empty(t, graph.Related(graph.Data()[0], "missing"))
+}
+
+func TestResolvedGraph_Path(t *testing.T) {
+	payload := new(jsonapi.ManyPayload)
+	if err := json.Unmarshal([]byte(compoundDocument), payload); err != nil {
+		t.Fatal(err)
+	}
+
+	graph := jsonapi.NewResolvedGraph(payload)
+
+	assert.Equal(t, []string{"3", "1", "2", "4"}, ids(graph.Path("comments")))
+
+	authors := graph.Path("comments.author")
+	assert.Equal(t, []string{"c", "b", "a"}, ids(authors))
+	assert.Equal(t, "Cat", authors[0].Attributes["name"])
+}
+
+func TestResolvedGraph_MarshaledPayload(t *testing.T) {
+	blog := testBlog()
+
+	payload, err := jsonapi.Marshal(blog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := jsonapi.NewResolvedGraph(payload)
+
+	posts := graph.Related(graph.Data()[0], "posts")
+	assert.Equal(t, []string{"1", "2"}, ids(posts))
+	assert.Equal(t, "Foo", posts[0].Attributes["title"])
+
+	assert.Equal(t, []string{"1"}, ids(graph.Path("current_post")))
+}
+
+const chainDocument = `{
+	"data": {"type": "posts", "id": "1", "relationships": {
+		"author": {"data": {"type": "people", "id": "a"}},
+		"tags": {"data": [{"type": "tags", "id": "t"}]}
+	}},
+	"included": [
+		{"type": "people", "id": "a", "relationships": {"employer": {"data": {"type": "companies", "id": "c"}}}},
+		{"type": "companies", "id": "c", "relationships": {"founder": {"data": {"type": "people", "id": "a"}}}},
+		{"type": "tags", "id": "t", "attributes": {"label": "go"}}
+	]
+}`
+
+func TestResolvedGraph_ResolveRelationships(t *testing.T) {
+	payload := new(jsonapi.OnePayload)
+	if err := json.Unmarshal([]byte(chainDocument), payload); err != nil {
+		t.Fatal(err)
+	}
+	graph := jsonapi.NewResolvedGraph(payload)
+
+	related := func(n *jsonapi.ResourceObj, relation string) *jsonapi.ResourceObj {
+		rel, ok := n.Relationships[relation].(*jsonapi.RelationshipOneNode)
+		if !ok {
+			t.Fatalf("Expected %s to be resolved into a to-one node, got %#v", relation, n.Relationships[relation])
+		}
+		return rel.Data
+	}
+
+	var tests = map[string]struct {
+		maxDepth int
+		expanded []string
+	}{
+		"depth 1":   {maxDepth: 1, expanded: []string{"author"}},
+		"depth 2":   {maxDepth: 2, expanded: []string{"author", "employer"}},
+		"unlimited": {maxDepth: 0, expanded: []string{"author", "employer"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			post := graph.ResolveRelationships(jsonapi.ResolveOptions{MaxDepth: test.maxDepth})[0]
+
+			tags := post.Relationships["tags"].(*jsonapi.RelationshipManyNode)
+			assert.Equal(t, "go", tags.Data[0].Attributes["label"])
+
+			author := related(post, "author")
+			assert.Contains(t, author.Relationships, "employer")
+
+			employer := author.Relationships["employer"]
+			if len(test.expanded) == 1 {
+				// beyond the depth the decoded linkage is left untouched
+				_, isNode := employer.(*jsonapi.RelationshipOneNode)
+				assert.False(t, isNode)
+				return
+			}
+
+			company := related(author, "employer")
+			assert.Contains(t, company.Relationships, "founder")
+
+			if test.maxDepth == 0 {
+				// the cycle back to the author stops at linkage
+				founder := related(company, "founder")
+				assert.Equal(t, "a", founder.ID)
+				assert.Nil(t, founder.Relationships)
+			}
+		})
+	}
+
+	// the included resources themselves are not modified
+	assert.Len(t, graph.Related(graph.Data()[0], "author")[0].Relationships, 1)
+	_, isNode := graph.Related(graph.Data()[0], "author")[0].Relationships["employer"].(*jsonapi.RelationshipOneNode)
+	assert.False(t, isNode)
+}
@@ -0,0 +1,309 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerForwardedProto = "X-Forwarded-Proto"
+	headerForwardedHost  = "X-Forwarded-Host"
+	headerLocation       = "Location"
+	headerRequestID      = "X-Request-Id"
+	headerLink           = "Link"
+	headerContentType    = "Content-Type"
+
+	metaKeyRequestID  = "request_id"
+	metaKeyAPIVersion = "api_version"
+
+	mediaTypeParamExt     = "ext"
+	mediaTypeParamProfile = "profile"
+)
+
+// ErrUnsupportedMediaType is returned by ValidateMediaType when a media type
+// is not the JSON API media type, or has parameters other than ext and
+// profile; servers respond to such a Content-Type with 415 Unsupported Media
+// Type.
+var ErrUnsupportedMediaType = errors.New("media type must be application/vnd.api+json with no parameters other than ext and profile")
+
+// ValidateMediaType checks a Content-Type header value, e.g.
+// r.Header.Get("Content-Type"), against the JSON API media type. The ext and
+// profile parameters of JSON API 1.1 are accepted; any other parameter, such
+// as charset, is not.
+//
+// http://jsonapi.org/format/#content-negotiation-servers
+func ValidateMediaType(value string) error {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil || mediaType != MediaType {
+		return ErrUnsupportedMediaType
+	}
+
+	for name := range params {
+		if name != mediaTypeParamExt && name != mediaTypeParamProfile {
+			return ErrUnsupportedMediaType
+		}
+	}
+
+	return nil
+}
+
+// SelfLinkOptions configures how PopulateSelfLinkWithOptions derives the self
+// link from a request.
+type SelfLinkOptions struct {
+	// TrustForwardedHeaders uses the X-Forwarded-Proto and X-Forwarded-Host
+	// headers, when present, for the scheme and host of the link. Only enable
+	// this behind a proxy that sets these headers.
+	TrustForwardedHeaders bool
+}
+
+// PopulateSelfLink sets the top level "self" link of the payload to the URL of
+// the request, including its query string.
+func PopulateSelfLink(p Payloader, r *http.Request) {
+	PopulateSelfLinkWithOptions(p, r, SelfLinkOptions{})
+}
+
+// PopulateSelfLinkWithOptions does the same as PopulateSelfLink, configured by
+// opts.
+func PopulateSelfLinkWithOptions(p Payloader, r *http.Request, opts SelfLinkOptions) {
+	p.setLink("self", requestURL(r, opts))
+}
+
+// SetLocation sets the Location header of a response to a resource creation
+// request to the URL of obj, of the form baseURL/TYPES/ID as for
+// ApplySelfLinks.
+func SetLocation(w http.ResponseWriter, obj *ResourceObj, baseURL string) {
+	w.Header().Set(headerLocation, resourceURL(baseURL, obj))
+}
+
+// RequestMetaOptions configures the meta PopulateRequestMeta derives from a
+// request.
+type RequestMetaOptions struct {
+	// RequestIDHeader is the header holding the id of the request, added to
+	// the meta as "request_id"; it defaults to X-Request-Id.
+	RequestIDHeader string
+
+	// APIVersion, when set, is added to the meta as "api_version".
+	APIVersion string
+}
+
+// PopulateRequestMeta adds the observability meta of the request r, its
+// request id and the API version of opts, to the document meta of p. Keys the
+// meta already has are kept as they are, and absent values are not added.
+func PopulateRequestMeta(p Payloader, r *http.Request, opts RequestMetaOptions) {
+	header := opts.RequestIDHeader
+	if header == "" {
+		header = headerRequestID
+	}
+
+	var existing Meta
+	switch payload := p.(type) {
+	case *OnePayload:
+		if payload.Meta != nil {
+			existing = *payload.Meta
+		}
+	case *ManyPayload:
+		if payload.Meta != nil {
+			existing = *payload.Meta
+		}
+	}
+
+	for key, value := range map[string]string{
+		metaKeyRequestID:  r.Header.Get(header),
+		metaKeyAPIVersion: opts.APIVersion,
+	} {
+		if _, ok := existing[key]; ok || value == "" {
+			continue
+		}
+		p.setMeta(key, value)
+	}
+}
+
+// WriteLinkHeader adds the first, prev, next and last pagination links of
+// links to the Link header of the response, as RFC 5988 entries of the form
+// <URL>; rel="next", for clients that read pagination from the headers
+// rather than the document. Null or absent links are left out.
+func WriteLinkHeader(w http.ResponseWriter, links Links) {
+	var entries []string
+	for _, rel := range []string{KeyFirstPage, KeyPreviousPage, KeyNextPage, KeyLastPage} {
+		if href, ok := linkHref(links[rel]); ok && href != "" {
+			entries = append(entries, fmt.Sprintf(`<%s>; rel="%s"`, href, rel))
+		}
+	}
+
+	if len(entries) > 0 {
+		w.Header().Add(headerLink, strings.Join(entries, ", "))
+	}
+}
+
+// ParseLinkHeader parses the entries of an RFC 5988 Link header, as written
+// by WriteLinkHeader, into links keyed by their rel, e.g. next, for clients
+// of APIs that paginate through headers. The rel of an entry may be quoted or
+// not, and may hold several space separated rels; entries without a rel are
+// left out.
+func ParseLinkHeader(header string) Links {
+	links := Links{}
+
+	for {
+		start := strings.IndexByte(header, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(header[start:], '>')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		href := header[start+1 : end]
+		params, rest := splitLinkParams(header[end+1:])
+		header = rest
+
+		for _, param := range strings.Split(params, ";") {
+			parts := strings.SplitN(param, "=", 2)
+			if len(parts) != 2 || !strings.EqualFold(strings.TrimSpace(parts[0]), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(parts[1]), `"`)) {
+				links[rel] = href
+			}
+		}
+	}
+
+	return links
+}
+
+// splitLinkParams splits the parameters of a Link header entry, up to the
+// comma ending the entry outside of a quoted string, from the entries after
+// it.
+func splitLinkParams(header string) (params, rest string) {
+	quoted := false
+	for i, c := range header {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			return header[:i], header[i+1:]
+		}
+	}
+	return header, ""
+}
+
+// PageLimitMode selects what EnforcePageLimit does with a request asking for
+// more resources per page than allowed.
+type PageLimitMode int
+
+const (
+	// PageLimitReject responds 400 Bad Request with an error document.
+	PageLimitReject PageLimitMode = iota
+	// PageLimitClamp lowers the page size of the request to the maximum
+	// before passing it on.
+	PageLimitClamp
+)
+
+// PageLimitOptions configures EnforcePageLimit.
+type PageLimitOptions struct {
+	// Max is the largest page size allowed; zero or less allows any.
+	Max int64
+	// Mode is what is done with requests exceeding Max.
+	Mode PageLimitMode
+}
+
+// pageSizeParams are the query parameters checked by EnforcePageLimit.
+var pageSizeParams = map[string]bool{
+	QueryParamPageLimit: true,
+	QueryParamPageSize:  true,
+	QueryParamPerPage:   true,
+}
+
+// EnforcePageLimit wraps next so that requests with a page[limit],
+// page[size] or per_page parameter above opts.Max are rejected or clamped,
+// according to opts.Mode, before they reach it, so that the page size policy
+// is applied in one place rather than by every handler. Parameters that are
+// not numbers are left for the handler to reject.
+func EnforcePageLimit(next http.Handler, opts PageLimitOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.Max <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Rewrite only the offending values in the raw query, keeping the
+		// encoding of the rest, so that the page parameters can still be
+		// matched literally, e.g. by OffsetPagination. Every value of a
+		// repeated parameter is checked, as handlers may read any of them
+		pairs := strings.Split(r.URL.RawQuery, "&")
+		exceeded := false
+		for i, pair := range pairs {
+			key, value := pair, ""
+			if j := strings.IndexByte(pair, '='); j >= 0 {
+				key, value = pair[:j], pair[j+1:]
+			}
+			param, err := url.QueryUnescape(key)
+			if err != nil || !pageSizeParams[param] {
+				continue
+			}
+			if value, err = url.QueryUnescape(value); err != nil {
+				continue
+			}
+
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size <= opts.Max {
+				continue
+			}
+
+			if opts.Mode == PageLimitReject {
+				w.Header().Set(headerContentType, MediaType)
+				w.WriteHeader(http.StatusBadRequest)
+				MarshalErrors(w, []*ErrorObject{{
+					Status: strconv.Itoa(http.StatusBadRequest),
+					Title:  StatusText(http.StatusBadRequest),
+					Detail: fmt.Sprintf("The %s parameter must not exceed %d", param, opts.Max),
+					Source: &ErrorSource{Parameter: param},
+				}})
+				return
+			}
+
+			pairs[i] = key + "=" + strconv.FormatInt(opts.Max, 10)
+			exceeded = true
+		}
+
+		if exceeded {
+			r = r.Clone(r.Context())
+			r.URL.RawQuery = strings.Join(pairs, "&")
+			r.RequestURI = r.URL.RequestURI()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestURL rebuilds the absolute URL the client used to make the request.
+func requestURL(r *http.Request, opts SelfLinkOptions) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+
+	if opts.TrustForwardedHeaders {
+		if proto := firstHeaderValue(r, headerForwardedProto); proto != "" {
+			scheme = proto
+		}
+		if forwardedHost := firstHeaderValue(r, headerForwardedHost); forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	return scheme + "://" + host + r.URL.RequestURI()
+}
+
+// firstHeaderValue returns the first of the comma separated values of the
+// header, as appended to by each proxy a request passes through.
+func firstHeaderValue(r *http.Request, header string) string {
+	value := strings.SplitN(r.Header.Get(header), ",", 2)[0]
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,234 @@
+package jsonapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestPopulateSelfLink(t *testing.T) {
+	var tests = map[string]struct {
+		target   string
+		headers  map[string]string
+		opts     jsonapi.SelfLinkOptions
+		expected string
+	}{
+		"path and query": {
+			target:   "http://example.com/blogs?page[limit]=10&page[offset]=20",
+			expected: "http://example.com/blogs?page[limit]=10&page[offset]=20",
+		},
+		"tls": {
+			target:   "https://example.com/blogs/1",
+			expected: "https://example.com/blogs/1",
+		},
+		"forwarded headers ignored by default": {
+			target: "http://internal:8080/blogs",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "api.example.com",
+			},
+			expected: "http://internal:8080/blogs",
+		},
+		"forwarded headers trusted": {
+			target: "http://internal:8080/blogs?sort=-title",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https, http",
+				"X-Forwarded-Host":  "api.example.com, proxy.internal",
+			},
+			opts:     jsonapi.SelfLinkOptions{TrustForwardedHeaders: true},
+			expected: "https://api.example.com/blogs?sort=-title",
+		},
+		"trusted without forwarded headers": {
+			target:   "http://internal:8080/blogs",
+			opts:     jsonapi.SelfLinkOptions{TrustForwardedHeaders: true},
+			expected: "http://internal:8080/blogs",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.target, nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+
+			payload := &jsonapi.ManyPayload{}
+			jsonapi.PopulateSelfLinkWithOptions(payload, r, test.opts)
+
+			assert.Equal(t, &jsonapi.Links{"self": test.expected}, payload.Links)
+		})
+	}
+}
+
+func TestPopulateSelfLink_KeepsExistingLinks(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/blogs/1", nil)
+
+	payload := &jsonapi.OnePayload{Links: &jsonapi.Links{"related": "http://example.com/authors/1"}}
+	jsonapi.PopulateSelfLink(payload, r)
+
+	assert.Equal(t, &jsonapi.Links{
+		"self":    "http://example.com/blogs/1",
+		"related": "http://example.com/authors/1",
+	}, payload.Links)
+}
+
+func TestValidateMediaType(t *testing.T) {
+	var tests = map[string]struct {
+		value string
+		err   error
+	}{
+		"plain":               {value: "application/vnd.api+json"},
+		"ext":                 {value: `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`},
+		"profile":             {value: `application/vnd.api+json;profile="http://example.com/last-modified http://example.com/timestamps"`},
+		"ext and profile":     {value: `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"; profile="http://example.com/timestamps"`},
+		"charset":             {value: "application/vnd.api+json; charset=utf-8", err: jsonapi.ErrUnsupportedMediaType},
+		"ext and charset":     {value: `application/vnd.api+json; ext="x"; charset=utf-8`, err: jsonapi.ErrUnsupportedMediaType},
+		"other media type":    {value: "application/json", err: jsonapi.ErrUnsupportedMediaType},
+		"missing":             {value: "", err: jsonapi.ErrUnsupportedMediaType},
+		"malformed parameter": {value: "application/vnd.api+json; ext", err: jsonapi.ErrUnsupportedMediaType},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.err, jsonapi.ValidateMediaType(test.value))
+		})
+	}
+}
+
+func TestSetLocation(t *testing.T) {
+	w := httptest.NewRecorder()
+	jsonapi.SetLocation(w, &jsonapi.ResourceObj{Type: "photo", ID: "550e8400"}, "https://example.com/")
+
+	assert.Equal(t, "https://example.com/photos/550e8400", w.Header().Get("Location"))
+}
+
+func TestPopulateRequestMeta(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/blogs", nil)
+	r.Header.Set("X-Request-Id", "req-123")
+
+	p := &jsonapi.ManyPayload{Meta: &jsonapi.Meta{"total": 3}}
+	jsonapi.PopulateRequestMeta(p, r, jsonapi.RequestMetaOptions{APIVersion: "2016-08-17"})
+	assert.Equal(t, &jsonapi.Meta{
+		"total":       3,
+		"request_id":  "req-123",
+		"api_version": "2016-08-17",
+	}, p.Meta)
+
+	r.Header.Set("X-Correlation-Id", "corr-456")
+	one := &jsonapi.OnePayload{Meta: &jsonapi.Meta{"api_version": "pinned"}}
+	jsonapi.PopulateRequestMeta(one, r, jsonapi.RequestMetaOptions{RequestIDHeader: "X-Correlation-Id", APIVersion: "2016-08-17"})
+	assert.Equal(t, &jsonapi.Meta{
+		"request_id":  "corr-456",
+		"api_version": "pinned",
+	}, one.Meta)
+
+	empty := &jsonapi.OnePayload{}
+	jsonapi.PopulateRequestMeta(empty, httptest.NewRequest("GET", "/blogs", nil), jsonapi.RequestMetaOptions{})
+	assert.Nil(t, empty.Meta)
+}
+
+func TestWriteLinkHeader(t *testing.T) {
+	w := httptest.NewRecorder()
+	jsonapi.WriteLinkHeader(w, jsonapi.Links{
+		"self":  "/articles?page[offset]=100",
+		"first": "/articles?page[offset]=0",
+		"prev":  jsonapi.Link{Href: "/articles?page[offset]=0"},
+		"next":  "/articles?page[offset]=200",
+		"last":  nil,
+	})
+
+	assert.Equal(t, `</articles?page[offset]=0>; rel="first", `+
+		`</articles?page[offset]=0>; rel="prev", `+
+		`</articles?page[offset]=200>; rel="next"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	jsonapi.WriteLinkHeader(w, jsonapi.Links{"self": "/articles"})
+	assert.Empty(t, w.Header().Values("Link"))
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	header := `</articles?page[offset]=0&fields[articles]=title,body>; rel="first prev", ` +
+		`</articles?page[offset]=200>; rel=next; title="Next, please", ` +
+		`</articles?page[offset]=300>; REL="last", ` +
+		`</about>; type="text/html"`
+
+	assert.Equal(t, jsonapi.Links{
+		"first": "/articles?page[offset]=0&fields[articles]=title,body",
+		"prev":  "/articles?page[offset]=0&fields[articles]=title,body",
+		"next":  "/articles?page[offset]=200",
+		"last":  "/articles?page[offset]=300",
+	}, jsonapi.ParseLinkHeader(header))
+
+	w := httptest.NewRecorder()
+	links := jsonapi.Links{"first": "/a?page=1", "next": "/a?page=3", "last": "/a?page=9"}
+	jsonapi.WriteLinkHeader(w, links)
+	assert.Equal(t, links, jsonapi.ParseLinkHeader(w.Header().Get("Link")))
+
+	assert.Equal(t, jsonapi.Links{}, jsonapi.ParseLinkHeader(""))
+}
+
+func TestEnforcePageLimit(t *testing.T) {
+	var received string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.Query().Encode()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var tests = map[string]struct {
+		mode     jsonapi.PageLimitMode
+		target   string
+		status   int
+		received string
+	}{
+		"reject within the limit": {mode: jsonapi.PageLimitReject, target: "/posts?page[limit]=50", status: http.StatusOK, received: "page%5Blimit%5D=50"},
+		"reject over the limit":   {mode: jsonapi.PageLimitReject, target: "/posts?page[size]=51", status: http.StatusBadRequest},
+		"clamp within the limit":  {mode: jsonapi.PageLimitClamp, target: "/posts?page[size]=10", status: http.StatusOK, received: "page%5Bsize%5D=10"},
+		"clamp over the limit":    {mode: jsonapi.PageLimitClamp, target: "/posts?page[limit]=500&page[offset]=20", status: http.StatusOK, received: "page%5Blimit%5D=50&page%5Boffset%5D=20"},
+		"not a number":            {mode: jsonapi.PageLimitReject, target: "/posts?page[limit]=all", status: http.StatusOK, received: "page%5Blimit%5D=all"},
+		"reject a repeated value": {mode: jsonapi.PageLimitReject, target: "/posts?page[limit]=10&page[limit]=1000", status: http.StatusBadRequest},
+		"clamp a repeated value":  {mode: jsonapi.PageLimitClamp, target: "/posts?page[limit]=10&page[limit]=1000", status: http.StatusOK, received: "page%5Blimit%5D=10&page%5Blimit%5D=50"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			received = ""
+			handler := jsonapi.EnforcePageLimit(next, jsonapi.PageLimitOptions{Max: 50, Mode: test.mode})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+
+			assert.Equal(t, test.status, w.Code)
+			assert.Equal(t, test.received, received)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	jsonapi.EnforcePageLimit(next, jsonapi.PageLimitOptions{Max: 50}).ServeHTTP(w, httptest.NewRequest("GET", "/posts?page[size]=51", nil))
+	assert.Equal(t, jsonapi.MediaType, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"errors": [{
+		"status": "400",
+		"title": "Bad Request",
+		"detail": "The page[size] parameter must not exceed 50",
+		"source": {"parameter": "page[size]"}
+	}]}`, w.Body.String())
+}
+
+func TestEnforcePageLimit_OffsetPagination(t *testing.T) {
+	var links *jsonapi.Links
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links = jsonapi.NewOffsetPagination(r.URL.String(), 1000).GeneratePagination()
+	})
+	handler := jsonapi.EnforcePageLimit(next, jsonapi.PageLimitOptions{Max: 50, Mode: jsonapi.PageLimitClamp})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/posts?page[limit]=500&page[offset]=100", nil))
+
+	assert.Equal(t, &jsonapi.Links{
+		jsonapi.KeyFirstPage:    "/posts?page[limit]=50&page[offset]=0",
+		jsonapi.KeyPreviousPage: "/posts?page[limit]=50&page[offset]=50",
+		jsonapi.KeyNextPage:     "/posts?page[limit]=50&page[offset]=150",
+		jsonapi.KeyLastPage:     "/posts?page[limit]=50&page[offset]=950",
+	}, links)
+}
@@ -0,0 +1,192 @@
+package jsonapi
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrExpectedRelationship is returned by SetRelationshipLinks when it is not
+// given a relationship node.
+var ErrExpectedRelationship = errors.New("node should be a relationship node")
+
+// Pluralizer inflects a singular resource type name into the plural form
+// used in URL paths, e.g. "category" into "categories".
+type Pluralizer interface {
+	Pluralize(word string) string
+}
+
+// DefaultPluralizer is the Pluralizer used by ApplySelfLinks.
+var DefaultPluralizer Pluralizer = SimplePluralizer{}
+
+// SimplePluralizer is a Pluralizer following the regular English rules: a
+// consonant followed by "y" becomes "ies", words ending in "s", "x", "z",
+// "ch" or "sh" gain "es", and all others gain "s". Words that are already
+// plural, or irregular, can be listed in Overrides, e.g. "person": "people".
+type SimplePluralizer struct {
+	Overrides map[string]string
+}
+
+func (p SimplePluralizer) Pluralize(word string) string {
+	if plural, ok := p.Overrides[word]; ok {
+		return plural
+	}
+
+	switch {
+	case word == "":
+		return word
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsAny(word[len(word)-2:len(word)-1], "aeiou"):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	}
+
+	return word + "s"
+}
+
+// ApplySelfLinks sets a self link of the form baseURL/TYPES/ID, with the type
+// pluralized by DefaultPluralizer and the id path-escaped, on every resource of p that has an id and
+// no self link yet.
+func ApplySelfLinks(p Payloader, baseURL string) {
+	for _, n := range payloadResources(p) {
+		if n == nil || n.ID == "" {
+			continue
+		}
+		if n.Links == nil {
+			n.Links = &Links{}
+		}
+		if _, ok := (*n.Links)["self"]; ok {
+			continue
+		}
+
+		(*n.Links)["self"] = resourceURL(baseURL, n)
+	}
+}
+
+// SetRelationshipLinks sets the self and related links of the relationship
+// node, a *RelationshipOneNode, *RelationshipManyNode or
+// *RelationshipMetaNode, from the templates selfTmpl and relatedTmpl, e.g.
+// "/{type}/{id}/relationships/{relation}", in which the {type}, {id} and
+// {relation} placeholders are replaced by the path-escaped typ, id and
+// relation. An empty template leaves its link as it is.
+func SetRelationshipLinks(node interface{}, selfTmpl, relatedTmpl string, typ, id, relation string) error {
+	var links **Links
+	switch rel := node.(type) {
+	case *RelationshipOneNode:
+		links = &rel.Links
+	case *RelationshipManyNode:
+		links = &rel.Links
+	case *RelationshipMetaNode:
+		links = &rel.Links
+	default:
+		return ErrExpectedRelationship
+	}
+
+	replacer := strings.NewReplacer(
+		"{type}", url.PathEscape(typ),
+		"{id}", url.PathEscape(id),
+		"{relation}", url.PathEscape(relation),
+	)
+
+	for key, tmpl := range map[string]string{"self": selfTmpl, "related": relatedTmpl} {
+		if tmpl == "" {
+			continue
+		}
+		if *links == nil {
+			*links = &Links{}
+		}
+		(**links)[key] = replacer.Replace(tmpl)
+	}
+
+	return nil
+}
+
+// RelationshipLinkOptions configures the relationship links set during
+// marshaling by MarshalOptions.RelationshipLinks.
+type RelationshipLinkOptions struct {
+	// BaseURL is prepended to both templates, e.g. "https://example.com/api".
+	BaseURL string
+	// SelfTemplate and RelatedTemplate are the templates of the self and
+	// related links, with the {type}, {id} and {relation} placeholders of
+	// SetRelationshipLinks. They default to
+	// "/{type}/{id}/relationships/{relation}" and "/{type}/{id}/{relation}".
+	SelfTemplate    string
+	RelatedTemplate string
+}
+
+// applyRelationshipLinks sets the self and related links, described by opts,
+// of every relationship of the resources of p that has an id. Links the
+// relationship already has, e.g. from RelationshipLinkable, are kept.
+func applyRelationshipLinks(p Payloader, opts *RelationshipLinkOptions) {
+	selfTmpl, relatedTmpl := opts.SelfTemplate, opts.RelatedTemplate
+	if selfTmpl == "" {
+		selfTmpl = "/{type}/{id}/relationships/{relation}"
+	}
+	if relatedTmpl == "" {
+		relatedTmpl = "/{type}/{id}/{relation}"
+	}
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+
+	for _, n := range payloadResources(p) {
+		if n == nil || n.ID == "" {
+			continue
+		}
+
+		for name, relationship := range n.Relationships {
+			var links **Links
+			switch rel := relationship.(type) {
+			case *RelationshipOneNode:
+				links = &rel.Links
+			case *RelationshipManyNode:
+				links = &rel.Links
+			case *RelationshipMetaNode:
+				links = &rel.Links
+			default:
+				continue
+			}
+
+			generated := new(RelationshipMetaNode)
+			SetRelationshipLinks(generated, baseURL+selfTmpl, baseURL+relatedTmpl, n.Type, n.ID, name)
+
+			// Copy the links rather than modifying those returned by a
+			// RelationshipLinkable
+			merged := Links{}
+			if *links != nil {
+				for key, link := range **links {
+					merged[key] = link
+				}
+			}
+			for key, link := range *generated.Links {
+				if _, ok := merged[key]; !ok {
+					merged[key] = link
+				}
+			}
+			*links = &merged
+		}
+	}
+}
+
+// resourceURL returns the URL of n of the form baseURL/TYPES/ID, with the id
+// path-escaped.
+func resourceURL(baseURL string, n *ResourceObj) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + DefaultPluralizer.Pluralize(n.Type) + "/" + url.PathEscape(n.ID)
+}
+
+// payloadResources returns the primary and included resources of p.
+func payloadResources(p Payloader) []*ResourceObj {
+	var resources []*ResourceObj
+
+	switch payload := p.(type) {
+	case *OnePayload:
+		if payload.Data != nil {
+			resources = append(resources, payload.Data)
+		}
+		resources = append(resources, payload.Included...)
+	case *ManyPayload:
+		resources = append(resources, payload.Data...)
+		resources = append(resources, payload.Included...)
+	}
+
+	return resources
+}
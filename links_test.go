@@ -0,0 +1,115 @@
+package jsonapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestSimplePluralizer(t *testing.T) {
+	var tests = map[string]string{
+		"post":     "posts",
+		"category": "categories",
+		"day":      "days",
+		"box":      "boxes",
+		"address":  "addresses",
+		"branch":   "branches",
+		"wish":     "wishes",
+		"person":   "people",
+		"series":   "series",
+	}
+
+	p := jsonapi.SimplePluralizer{Overrides: map[string]string{"person": "people", "series": "series"}}
+	for word, plural := range tests {
+		assert.Equal(t, plural, p.Pluralize(word), word)
+	}
+}
+
+func TestApplySelfLinks(t *testing.T) {
+	payload := &jsonapi.ManyPayload{
+		Data: []*jsonapi.ResourceObj{
+			{Type: "category", ID: "1"},
+			{Type: "category", ID: "2", Links: &jsonapi.Links{"self": "/custom/2"}},
+		},
+		Included: []*jsonapi.ResourceObj{{Type: "person", ID: "9"}},
+	}
+
+	defer func(p jsonapi.Pluralizer) { jsonapi.DefaultPluralizer = p }(jsonapi.DefaultPluralizer)
+	jsonapi.DefaultPluralizer = jsonapi.SimplePluralizer{Overrides: map[string]string{"person": "people"}}
+
+	jsonapi.ApplySelfLinks(payload, "https://example.com/")
+
+	assert.Equal(t, &jsonapi.Links{"self": "https://example.com/categories/1"}, payload.Data[0].Links)
+	assert.Equal(t, &jsonapi.Links{"self": "/custom/2"}, payload.Data[1].Links)
+	assert.Equal(t, &jsonapi.Links{"self": "https://example.com/people/9"}, payload.Included[0].Links)
+}
+
+func TestApplySelfLinks_EscapesID(t *testing.T) {
+	payload := &jsonapi.OnePayload{Data: &jsonapi.ResourceObj{Type: "file", ID: "docs/a b?.txt"}}
+	jsonapi.ApplySelfLinks(payload, "https://example.com")
+	assert.Equal(t, &jsonapi.Links{"self": "https://example.com/files/docs%2Fa%20b%3F.txt"}, payload.Data.Links)
+}
+
+func TestSetRelationshipLinks(t *testing.T) {
+	const (
+		selfTmpl    = "/{type}/{id}/relationships/{relation}"
+		relatedTmpl = "/{type}/{id}/{relation}"
+	)
+
+	one := jsonapi.NewRelationshipOne("people", "9")
+	assert.NoError(t, jsonapi.SetRelationshipLinks(one, selfTmpl, relatedTmpl, "articles", "1", "author"))
+	assert.Equal(t, &jsonapi.Links{
+		"self":    "/articles/1/relationships/author",
+		"related": "/articles/1/author",
+	}, one.Links)
+
+	many := &jsonapi.RelationshipManyNode{Links: &jsonapi.Links{"self": "/custom"}}
+	assert.NoError(t, jsonapi.SetRelationshipLinks(many, "", relatedTmpl, "articles", "a/b", "tags"))
+	assert.Equal(t, &jsonapi.Links{
+		"self":    "/custom",
+		"related": "/articles/a%2Fb/tags",
+	}, many.Links)
+
+	assert.Equal(t, jsonapi.ErrExpectedRelationship,
+		jsonapi.SetRelationshipLinks(&jsonapi.ResourceObj{}, selfTmpl, relatedTmpl, "articles", "1", "author"))
+}
+
+func TestMarshalWithOptions_RelationshipLinks(t *testing.T) {
+	p, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{
+		RelationshipLinks: &jsonapi.RelationshipLinkOptions{BaseURL: "https://example.com/api/"},
+	})
+	assert.NoError(t, err)
+	one := p.(*jsonapi.OnePayload)
+
+	posts := one.Data.Relationships["posts"].(*jsonapi.RelationshipManyNode)
+	assert.Equal(t, "https://example.com/api/blogs/5/relationships/posts", (*posts.Links)["self"])
+	assert.Equal(t, "https://example.com/api/blogs/5/posts", (*posts.Links)["related"].(jsonapi.Link).Href,
+		"links from RelationshipLinkable are kept")
+
+	for _, n := range one.Included {
+		if n.Type != "posts" {
+			continue
+		}
+		comments := n.Relationships["comments"].(*jsonapi.RelationshipManyNode)
+		assert.Equal(t, &jsonapi.Links{
+			"self":    "https://example.com/api/posts/" + n.ID + "/relationships/comments",
+			"related": "https://example.com/api/posts/" + n.ID + "/comments",
+		}, comments.Links, "included resources get links too")
+	}
+	assert.NoError(t, jsonapi.MarshalValidate(p))
+
+	p, err = jsonapi.MarshalWithOptions(&Post{ID: 1}, jsonapi.MarshalOptions{
+		RelationshipLinks: &jsonapi.RelationshipLinkOptions{
+			SelfTemplate:    "/v2/{type}/{id}/links/{relation}",
+			RelatedTemplate: "/v2/{relation}?filter[{type}]={id}",
+		},
+	})
+	assert.NoError(t, err)
+	latest := p.(*jsonapi.OnePayload).Data.Relationships["latest_comment"].(*jsonapi.RelationshipOneNode)
+	assert.Equal(t, &jsonapi.Links{
+		"self":    "/v2/posts/1/links/latest_comment",
+		"related": "/v2/latest_comment?filter[posts]=1",
+	}, latest.Links, "null relationships get links from the custom templates")
+}
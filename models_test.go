@@ -2,6 +2,7 @@ package jsonapi_test
 
 import (
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/elasticpath/jsonapi"
@@ -164,6 +165,113 @@ func (bc *BadComment) JSONAPILinks() *jsonapi.Links {
 	}
 }
 
+type BadRelationshipLinks struct {
+	ID      uint64   `jsonapi:"primary,bad-relationship-links"`
+	Comment *Comment `jsonapi:"relation,comment"`
+}
+
+func (b *BadRelationshipLinks) JSONAPIRelationshipLinks(relation string) *jsonapi.Links {
+	return &jsonapi.Links{
+		"related": 42,
+	}
+}
+
+type Counted struct {
+	ID string `jsonapi:"primary,counted"`
+}
+
+func (c *Counted) JSONAPIMeta() *jsonapi.Meta {
+	return &jsonapi.Meta{
+		"total":   334,
+		"ratio":   0.25,
+		"label":   "counted",
+		"nested":  map[string]interface{}{"pages": int64(34)},
+		"samples": []interface{}{1, "two"},
+	}
+}
+
+type Member struct {
+	ID     string  `jsonapi:"primary,members"`
+	Role   *string `jsonapi:"meta,role,omitempty"`
+	Source *string `jsonapi:"meta,source,omitempty"`
+}
+
+type Squad struct {
+	ID      string    `jsonapi:"primary,squads"`
+	Lead    *Member   `jsonapi:"relation,lead"`
+	Members []*Member `jsonapi:"relation,members"`
+}
+
+type Ledger struct {
+	ID      string     `jsonapi:"primary,ledgers"`
+	Balance *big.Int   `jsonapi:"attr,balance"`
+	Rate    *big.Float `jsonapi:"attr,rate,omitempty"`
+}
+
+type ReservedAttribute struct {
+	ID   string `jsonapi:"primary,reserved"`
+	Kind string `jsonapi:"attr,type"`
+}
+
+type Embedding struct {
+	ID  string               `jsonapi:"primary,embeddings"`
+	Doc *jsonapi.ResourceObj `jsonapi:"attr,doc,omitempty"`
+}
+
+type Timestamps struct {
+	CreatedAt time.Time `jsonapi:"attr,created_at,iso8601"`
+	Label     string    `jsonapi:"attr,label"`
+}
+
+type Named struct {
+	Name  string `jsonapi:"attr,name"`
+	Label string `jsonapi:"attr,label"`
+}
+
+type Article struct {
+	Timestamps
+	*Named
+	ID     string   `jsonapi:"primary,articles"`
+	Label  string   `jsonapi:"attr,label,omitempty"`
+	Author *Comment `jsonapi:"relation,author"`
+}
+
+type Tagged struct {
+	Timestamps
+	Named
+	ID string `jsonapi:"primary,tagged"`
+}
+
+type Labelled struct {
+	Label string `jsonapi:"attr,label,omitempty"`
+}
+
+type Stamped struct {
+	Timestamps
+}
+
+type Layered struct {
+	Labelled
+	Stamped
+	ID string `jsonapi:"primary,layered"`
+}
+
+type Reviewed struct {
+	Reviewer *Comment `jsonapi:"relation,reviewer"`
+	Note     *Comment `jsonapi:"relation,note"`
+}
+
+type Shelf struct {
+	Books []*Book `jsonapi:"relation,books"`
+	ID    string  `jsonapi:"primary,shelves"`
+}
+
+type Review struct {
+	Reviewed
+	ID       string   `jsonapi:"primary,reviews"`
+	Reviewer *Comment `jsonapi:"relation,reviewer"`
+}
+
 type Company struct {
 	ID        string    `jsonapi:"primary,companies"`
 	Name      string    `jsonapi:"attr,name"`
@@ -0,0 +1,133 @@
+package jsonapi
+
+// defaultPageLimit is the page size of an OffsetPagination built by
+// NewOffsetPagination without WithDefaultLimit.
+const defaultPageLimit = 100
+
+// PaginationOption configures the OffsetPagination built by
+// NewOffsetPagination.
+type PaginationOption func(*OffsetPagination)
+
+// NewOffsetPagination returns an OffsetPagination of a result set of total
+// resources, for the request URL requestURL, configured by opts. Without
+// WithDefaultLimit, pages hold 100 resources.
+//
+//	p := jsonapi.NewOffsetPagination(r.URL.String(), total,
+//		jsonapi.WithDefaultLimit(20),
+//		jsonapi.WithMaxLimit(500),
+//		jsonapi.WithBaseURL("https://api.example.com"),
+//	)
+func NewOffsetPagination(requestURL string, total int64, opts ...PaginationOption) *OffsetPagination {
+	p := &OffsetPagination{URL: requestURL, Limit: defaultPageLimit, Total: total}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// WithDefaultLimit sets the page size of requests without a page[limit].
+// Unless WithMaxLimit is given, it is the largest page size too.
+func WithDefaultLimit(limit int64) PaginationOption {
+	return func(p *OffsetPagination) {
+		p.Limit = limit
+	}
+}
+
+// WithMaxLimit sets the largest page[limit] a request may ask for; larger
+// limits are clamped to it.
+func WithMaxLimit(limit int64) PaginationOption {
+	return func(p *OffsetPagination) {
+		p.maxLimit = limit
+	}
+}
+
+// WithBaseURL resolves path-relative page links, e.g. those of a request URL
+// without scheme and host, against baseURL, e.g. "https://api.example.com".
+func WithBaseURL(baseURL string) PaginationOption {
+	return func(p *OffsetPagination) {
+		p.baseURL = baseURL
+	}
+}
+
+// WithKeyNamer renames the keys of the links returned by GeneratePagination,
+// e.g. "prev" to "previous", for clients that expect other names.
+func WithKeyNamer(namer func(key string) string) PaginationOption {
+	return func(p *OffsetPagination) {
+		p.keyNamer = namer
+	}
+}
+
+// MarshalOption sets a field of the MarshalOptions built by
+// NewMarshalOptions.
+type MarshalOption func(*MarshalOptions)
+
+// NewMarshalOptions returns the MarshalOptions configured by opts, for
+// MarshalWithOptions and MarshalPayloadWithOptions.
+func NewMarshalOptions(opts ...MarshalOption) MarshalOptions {
+	var options MarshalOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithIncludeZeroValues sets MarshalOptions.IncludeZeroValues.
+func WithIncludeZeroValues() MarshalOption {
+	return func(o *MarshalOptions) {
+		o.IncludeZeroValues = true
+	}
+}
+
+// WithMaxIncluded sets MarshalOptions.MaxIncluded, and TruncateIncluded when
+// truncate is set.
+func WithMaxIncluded(max int, truncate bool) MarshalOption {
+	return func(o *MarshalOptions) {
+		o.MaxIncluded = max
+		o.TruncateIncluded = truncate
+	}
+}
+
+// WithSortIncluded sets MarshalOptions.SortIncluded.
+func WithSortIncluded() MarshalOption {
+	return func(o *MarshalOptions) {
+		o.SortIncluded = true
+	}
+}
+
+// WithSortKeys sets MarshalOptions.SortKeys.
+func WithSortKeys() MarshalOption {
+	return func(o *MarshalOptions) {
+		o.SortKeys = true
+	}
+}
+
+// WithAllowedTypes sets MarshalOptions.AllowedTypes to types.
+func WithAllowedTypes(types ...string) MarshalOption {
+	return func(o *MarshalOptions) {
+		o.AllowedTypes = make(map[string]bool, len(types))
+		for _, typ := range types {
+			o.AllowedTypes[typ] = true
+		}
+	}
+}
+
+// UnmarshalOption sets a field of the UnmarshalOptions built by
+// NewUnmarshalOptions.
+type UnmarshalOption func(*UnmarshalOptions)
+
+// NewUnmarshalOptions returns the UnmarshalOptions configured by opts, for
+// UnmarshalPayloadWithOptions and UnmarshalManyPayloadWithOptions.
+func NewUnmarshalOptions(opts ...UnmarshalOption) UnmarshalOptions {
+	var options UnmarshalOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithDisallowUnknownFields sets UnmarshalOptions.DisallowUnknownFields.
+func WithDisallowUnknownFields() UnmarshalOption {
+	return func(o *UnmarshalOptions) {
+		o.DisallowUnknownFields = true
+	}
+}
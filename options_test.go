@@ -0,0 +1,64 @@
+package jsonapi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestNewOffsetPagination(t *testing.T) {
+	p := jsonapi.NewOffsetPagination("/articles?page[limit]=500&page[offset]=200", 1000,
+		jsonapi.WithDefaultLimit(20),
+		jsonapi.WithMaxLimit(200),
+		jsonapi.WithBaseURL("https://api.example.com/"),
+		jsonapi.WithKeyNamer(func(key string) string {
+			if key == jsonapi.KeyPreviousPage {
+				return "previous"
+			}
+			return key
+		}),
+	)
+
+	assert.Equal(t, &jsonapi.Links{
+		"first":    "https://api.example.com/articles?page[limit]=200&page[offset]=0",
+		"previous": "https://api.example.com/articles?page[limit]=200&page[offset]=0",
+		"next":     "https://api.example.com/articles?page[limit]=200&page[offset]=400",
+		"last":     "https://api.example.com/articles?page[limit]=200&page[offset]=800",
+	}, p.GeneratePagination())
+
+	defaults := jsonapi.NewOffsetPagination("/articles", 1000, jsonapi.WithDefaultLimit(20))
+	assert.Equal(t, "/articles?page[limit]=20&page[offset]=20", (*defaults.GeneratePagination())["next"])
+
+	assert.Equal(t, int64(100), jsonapi.NewOffsetPagination("/articles", 1000).Limit)
+}
+
+func TestNewMarshalOptions(t *testing.T) {
+	opts := jsonapi.NewMarshalOptions(
+		jsonapi.WithSortIncluded(),
+		jsonapi.WithSortKeys(),
+		jsonapi.WithMaxIncluded(2, true),
+		jsonapi.WithAllowedTypes("blogs", "posts", "comments"),
+	)
+	assert.Equal(t, jsonapi.MarshalOptions{
+		MaxIncluded:      2,
+		TruncateIncluded: true,
+		SortIncluded:     true,
+		SortKeys:         true,
+		AllowedTypes:     map[string]bool{"blogs": true, "posts": true, "comments": true},
+	}, opts)
+
+	p, err := jsonapi.MarshalWithOptions(testBlog(), opts)
+	assert.NoError(t, err)
+
+	var keys []string
+	for _, n := range p.(*jsonapi.OnePayload).Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	assert.Equal(t, []string{"comments,1", "comments,2"}, keys)
+
+	_, err = jsonapi.MarshalWithOptions(testBlog(), jsonapi.NewMarshalOptions(jsonapi.WithAllowedTypes("blogs")))
+	assert.True(t, errors.Is(err, jsonapi.ErrDisallowedType))
+}
@@ -0,0 +1,123 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// UnmarshalOrderedPayload decodes the document in into a *OnePayload, or a
+// *ManyPayload when its "data" is an array, recording the order in which the
+// attributes of each resource in "data" and "included" appeared on the wire,
+// which the Attributes map does not keep. The order is then available from
+// ResourceObj.AttributeOrder, e.g. to write audit logs matching the request.
+func UnmarshalOrderedPayload(in io.Reader) (Payloader, error) {
+	raw, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Data     json.RawMessage   `json:"data"`
+		Included []json.RawMessage `json:"included"`
+	}
+	if err := DefaultCodec.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	var payload Payloader
+	var data, included []*ResourceObj
+	var rawData []json.RawMessage
+
+	if trimmed := bytes.TrimSpace(doc.Data); len(trimmed) > 0 && trimmed[0] == '[' {
+		many := new(ManyPayload)
+		if err := DefaultCodec.Unmarshal(raw, many); err != nil {
+			return nil, err
+		}
+		if err := DefaultCodec.Unmarshal(trimmed, &rawData); err != nil {
+			return nil, err
+		}
+		payload, data, included = many, many.Data, many.Included
+	} else {
+		one := new(OnePayload)
+		if err := DefaultCodec.Unmarshal(raw, one); err != nil {
+			return nil, err
+		}
+		payload, data, included = one, []*ResourceObj{one.Data}, one.Included
+		rawData = []json.RawMessage{doc.Data}
+	}
+
+	if err := recordAttributeOrder(data, rawData); err != nil {
+		return nil, err
+	}
+	if err := recordAttributeOrder(included, doc.Included); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// AttributeOrder returns the names of the attributes of the resource in the
+// order they were decoded by UnmarshalOrderedPayload, or nil when it was not
+// decoded by it.
+func (n *ResourceObj) AttributeOrder() []string {
+	return n.attributeOrder
+}
+
+// recordAttributeOrder records the attribute order of each of resources from
+// the raw resource object at the same index of raw.
+func recordAttributeOrder(resources []*ResourceObj, raw []json.RawMessage) error {
+	for i, n := range resources {
+		if n == nil || i >= len(raw) {
+			continue
+		}
+
+		var resource struct {
+			Attributes json.RawMessage `json:"attributes"`
+		}
+		if err := DefaultCodec.Unmarshal(raw[i], &resource); err != nil {
+			return err
+		}
+
+		order, err := objectKeys(resource.Attributes)
+		if err != nil {
+			return err
+		}
+		n.attributeOrder = order
+	}
+	return nil
+}
+
+// objectKeys returns the keys of the JSON object raw in order, tokenizing
+// only its top level; null or an absent object has no keys. Codec has no
+// tokenizer, so this uses encoding/json, only to read the key order.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := dec.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		return nil, fmt.Errorf("The attributes member must be an object")
+	}
+
+	keys := []string{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
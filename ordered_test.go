@@ -0,0 +1,65 @@
+package jsonapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestUnmarshalOrderedPayload(t *testing.T) {
+	p, err := jsonapi.UnmarshalOrderedPayload(strings.NewReader(`{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"attributes": {"title": "Ordered", "body": {"z": 1, "a": 2}, "blog_id": 3, "author": null}
+		},
+		"included": [
+			{"type": "comments", "id": "1", "attributes": {"post_id": 1, "body": "first"}},
+			{"type": "comments", "id": "2"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	one := p.(*jsonapi.OnePayload)
+	assert.Equal(t, []string{"title", "body", "blog_id", "author"}, one.Data.AttributeOrder())
+	assert.Equal(t, "Ordered", one.Data.Attributes["title"])
+	assert.Equal(t, []string{"post_id", "body"}, one.Included[0].AttributeOrder())
+	assert.Nil(t, one.Included[1].AttributeOrder())
+
+	p, err = jsonapi.UnmarshalOrderedPayload(strings.NewReader(`{
+		"data": [
+			{"type": "posts", "id": "1", "attributes": {"title": "One", "body": "b"}},
+			{"type": "posts", "id": "2", "attributes": {"body": "b", "title": "Two"}}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	many := p.(*jsonapi.ManyPayload)
+	assert.Equal(t, []string{"title", "body"}, many.Data[0].AttributeOrder())
+	assert.Equal(t, []string{"body", "title"}, many.Data[1].AttributeOrder())
+
+	p, err = jsonapi.UnmarshalOrderedPayload(strings.NewReader(`{"data": null}`))
+	assert.NoError(t, err)
+	assert.Nil(t, p.(*jsonapi.OnePayload).Data)
+
+	assert.Nil(t, (&jsonapi.ResourceObj{}).AttributeOrder())
+}
+
+func TestUnmarshalOrderedPayload_DefaultCodec(t *testing.T) {
+	defer func(codec jsonapi.Codec) { jsonapi.DefaultCodec = codec }(jsonapi.DefaultCodec)
+	codec := new(countingCodec)
+	jsonapi.DefaultCodec = codec
+
+	_, err := jsonapi.UnmarshalOrderedPayload(strings.NewReader(`{"data": [{"type": "posts", "id": "1", "attributes": {"title": "Ordered"}}]}`))
+	assert.NoError(t, err)
+	// the document, its "data" array, the payload, its resource and the
+	// resource's attributes are all decoded with the codec
+	assert.GreaterOrEqual(t, codec.unmarshals, 5)
+}
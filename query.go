@@ -0,0 +1,445 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseFieldsets parses the fields[TYPE] parameters of a query into a map of
+// resource types to the requested field names.
+//
+// A parameter with an empty value, e.g. "fields[articles]=", requests no
+// fields for the type and is returned as an empty, non-nil, slice. Types
+// without a fields parameter are absent from the map. A parameter repeated
+// for a type, e.g. "fields[articles]=title&fields[articles]=body", requests
+// the union of its values, in the order they first appear.
+func ParseFieldsets(query url.Values) map[string][]string {
+	fieldsets := make(map[string][]string)
+
+	for key, values := range query {
+		typ, ok := bracketedParam(key, QueryParamFields)
+		if !ok {
+			continue
+		}
+
+		fields := []string{}
+		seen := make(map[string]bool)
+		for _, value := range values {
+			for _, field := range strings.Split(value, ",") {
+				if field == "" || seen[field] {
+					continue
+				}
+				seen[field] = true
+				fields = append(fields, field)
+			}
+		}
+		fieldsets[typ] = fields
+	}
+
+	return fieldsets
+}
+
+// SortField is a field of the sort parameter of a query, e.g. "-created" is
+// the created field in descending order.
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// ParseSort parses the sort parameter of a query into its sort fields, in
+// order of precedence. The parameter being absent returns nil.
+func ParseSort(query url.Values) []SortField {
+	value := query.Get(QueryParamSort)
+	if value == "" {
+		return nil
+	}
+
+	sorts := []SortField{}
+	for _, field := range strings.Split(value, ",") {
+		sortField := SortField{Field: field}
+		if strings.HasPrefix(field, "-") {
+			sortField = SortField{Field: field[1:], Descending: true}
+		}
+		if sortField.Field != "" {
+			sorts = append(sorts, sortField)
+		}
+	}
+	return sorts
+}
+
+// ValidateSort checks that each of sorts is on one of the allowed fields.
+// Servers respond to a sort on any other field with 400 Bad Request.
+func ValidateSort(sorts []SortField, allowed []string) error {
+	supported := make(map[string]bool, len(allowed))
+	for _, field := range allowed {
+		supported[field] = true
+	}
+
+	for _, sortField := range sorts {
+		if !supported[sortField.Field] {
+			return fmt.Errorf("The sort field %s is not supported", sortField.Field)
+		}
+	}
+	return nil
+}
+
+// ParseInclude parses the include parameter of a query into the requested
+// relationship paths, e.g. "comments.author".
+//
+// The parameter being absent, leaving the includes to the server default,
+// returns nil, while an empty value, e.g. "include=", explicitly requests no
+// related resources and is returned as an empty, non-nil, slice. Whitespace
+// around paths and their segments, e.g. "author, comments", is ignored.
+func ParseInclude(query url.Values) []string {
+	if _, ok := query[QueryParamInclude]; !ok {
+		return nil
+	}
+
+	paths := []string{}
+	for _, path := range strings.Split(query.Get(QueryParamInclude), ",") {
+		segments := strings.Split(path, ".")
+		for i, segment := range segments {
+			segments[i] = strings.TrimSpace(segment)
+		}
+		if path = strings.Join(segments, "."); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// FilterIncludes removes the resources from the "included" array of p that
+// are not reached by following the relationship paths of include, as parsed
+// by ParseInclude, from the primary data. A nil include, the parameter being
+// absent, leaves p untouched, while an empty include removes every included
+// resource.
+func FilterIncludes(p Payloader, include []string) {
+	if include == nil {
+		return
+	}
+
+	var current []*ResourceObj
+	var included *[]*ResourceObj
+	switch payload := p.(type) {
+	case *OnePayload:
+		if payload.Data != nil {
+			current = []*ResourceObj{payload.Data}
+		}
+		included = &payload.Included
+	case *ManyPayload:
+		current = payload.Data
+		included = &payload.Included
+	default:
+		return
+	}
+
+	index := make(map[string]*ResourceObj, len(*included))
+	for _, n := range *included {
+		index[resourceKey(n)] = n
+	}
+
+	requested := make(map[string]bool)
+	for _, path := range include {
+		resources := current
+		for _, name := range strings.Split(path, ".") {
+			var next []*ResourceObj
+			for _, n := range resources {
+				for _, linkage := range relationshipLinkage(n.Relationships[name]) {
+					if r, ok := index[resourceKey(linkage)]; ok {
+						requested[resourceKey(r)] = true
+						next = append(next, r)
+					}
+				}
+			}
+			resources = next
+		}
+	}
+
+	filtered := []*ResourceObj{}
+	for _, n := range *included {
+		if requested[resourceKey(n)] {
+			filtered = append(filtered, n)
+		}
+	}
+	*included = filtered
+}
+
+// ApplyIncludeQuery parses the include parameter of query and removes the
+// resources from the "included" array of p that the request did not ask for,
+// as FilterIncludes does. The relationship linkage of the remaining resources
+// is kept, so that clients can still identify the related resources that were
+// not included. A request without an include parameter leaves p untouched.
+func ApplyIncludeQuery(p *ManyPayload, query url.Values) {
+	FilterIncludes(p, ParseInclude(query))
+}
+
+// ApplyFieldset removes the attributes and relationships of o that are not in
+// the fieldset requested for its type. Resources whose type has no fieldset
+// are left untouched, while an empty fieldset removes every field.
+func ApplyFieldset(o *ResourceObj, fieldsets map[string][]string) {
+	fields, ok := fieldsets[o.Type]
+	if !ok {
+		return
+	}
+
+	requested := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		requested[field] = true
+	}
+
+	for name := range o.Attributes {
+		if !requested[name] {
+			delete(o.Attributes, name)
+		}
+	}
+
+	for name := range o.Relationships {
+		if !requested[name] {
+			delete(o.Relationships, name)
+		}
+	}
+}
+
+// PageParams holds the pagination parameters of a request, whichever
+// pagination style it uses: page[limit] and page[offset], page[number] and
+// page[size] (or the scalar page and per_page), or page[cursor]. Parameters
+// absent from the request are zero.
+type PageParams struct {
+	Limit  int64
+	Offset int64
+	Number int64
+	Size   int64
+	Cursor string
+
+	invalid []string
+}
+
+// Validate returns an error naming the first parameter of the request that
+// was not a non-negative integer, if any.
+func (p PageParams) Validate() error {
+	if len(p.invalid) > 0 {
+		return fmt.Errorf("The %s query parameter must be a non-negative integer", p.invalid[0])
+	}
+	return nil
+}
+
+// ParsePageParams parses the pagination parameters of query. Invalid values,
+// either negative or not integers, are left as zero and reported by
+// Validate.
+func ParsePageParams(query url.Values) PageParams {
+	var p PageParams
+
+	parse := func(target *int64, names ...string) {
+		for _, name := range names {
+			value, ok := query[name]
+			if !ok || len(value) == 0 {
+				continue
+			}
+			n, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil || n < 0 {
+				p.invalid = append(p.invalid, name)
+				return
+			}
+			*target = n
+			return
+		}
+	}
+
+	parse(&p.Limit, QueryParamPageLimit)
+	parse(&p.Offset, QueryParamPageOffset)
+	parse(&p.Number, QueryParamPageNumber, QueryParamPage)
+	parse(&p.Size, QueryParamPageSize, QueryParamPerPage)
+	p.Cursor = query.Get(QueryParamPageCursor)
+
+	sort.Strings(p.invalid)
+
+	return p
+}
+
+// ParseFilters parses the filter parameters of a query into a map of filter
+// names to their values. Nested brackets are joined with dots, so
+// "filter[author][id]=5" is returned as {"author.id": ["5"]}. The values of a
+// repeated parameter are all returned, in order.
+func ParseFilters(query url.Values) map[string][]string {
+	filters := make(map[string][]string)
+
+	for key, values := range query {
+		path, ok := bracketedPath(key, QueryParamFilter)
+		if !ok {
+			continue
+		}
+
+		name := strings.Join(path, ".")
+		filters[name] = append(filters[name], values...)
+	}
+
+	return filters
+}
+
+// bracketedPath returns the names within each of the brackets of a query
+// parameter of the form family[name][nested]...
+func bracketedPath(key, family string) ([]string, bool) {
+	if !strings.HasPrefix(key, family+"[") || !strings.HasSuffix(key, "]") {
+		return nil, false
+	}
+
+	path := strings.Split(key[len(family)+1:len(key)-1], "][")
+	for _, name := range path {
+		if name == "" || strings.ContainsAny(name, "[]") {
+			return nil, false
+		}
+	}
+
+	return path, true
+}
+
+// bracketedParam returns the name within the brackets of a query parameter of
+// the form family[name].
+func bracketedParam(key, family string) (string, bool) {
+	if !strings.HasPrefix(key, family+"[") || !strings.HasSuffix(key, "]") {
+		return "", false
+	}
+
+	return key[len(family)+1 : len(key)-1], true
+}
+
+// Query holds the parsed JSON API parameters of a request query.
+type Query struct {
+	// Include is as returned by ParseInclude, nil without an include
+	// parameter.
+	Include []string
+	// Fields is as returned by ParseFieldsets.
+	Fields map[string][]string
+	// Filters is as returned by ParseFilters.
+	Filters map[string][]string
+	// Sort is as returned by ParseSort.
+	Sort []SortField
+	// Page is as returned by ParsePageParams.
+	Page PageParams
+}
+
+// QueryParamError is an invalid query parameter found by ParseQuery. It has
+// the 400 Bad Request status for ErrorsFromError.
+type QueryParamError struct {
+	Parameter string
+	Detail    string
+}
+
+func (e *QueryParamError) Error() string {
+	return e.Detail
+}
+
+// StatusCode returns http.StatusBadRequest.
+func (e *QueryParamError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// QueryErrors is returned by ParseQuery with every invalid parameter of a
+// query, so that a client is told about all of them at once;
+// ErrorsFromError gives an error object per QueryParamError.
+type QueryErrors []error
+
+func (e QueryErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors of each invalid parameter.
+func (e QueryErrors) Unwrap() []error {
+	return e
+}
+
+// queryFamilies are the query parameter families ParseQuery understands.
+var queryFamilies = map[string]bool{
+	QueryParamInclude:   true,
+	QueryParamSort:      true,
+	QueryParamFields:    true,
+	QueryParamFilter:    true,
+	QueryParamPage:      true,
+	QueryParamSignature: true,
+}
+
+// queryPageParams are the page parameters ParseQuery understands.
+var queryPageParams = map[string]bool{
+	QueryParamPage:       true,
+	QueryParamPageLimit:  true,
+	QueryParamPageOffset: true,
+	QueryParamPageNumber: true,
+	QueryParamPageSize:   true,
+	QueryParamPageCursor: true,
+}
+
+// ParseQuery parses all of the JSON API parameters of query into a Query.
+// The query is also validated: a fields parameter must name a type, include
+// paths must not have empty segments, page parameters must be known and, for
+// the numeric ones, non-negative integers, and parameters whose names are all
+// lowercase letters, which the specification reserves, must be known. Every
+// invalid parameter is returned, as a QueryParamError within QueryErrors,
+// along with what could be parsed.
+//
+// http://jsonapi.org/format/#query-parameters
+func ParseQuery(query url.Values) (Query, error) {
+	q := Query{
+		Include: ParseInclude(query),
+		Fields:  ParseFieldsets(query),
+		Filters: ParseFilters(query),
+		Sort:    ParseSort(query),
+		Page:    ParsePageParams(query),
+	}
+
+	var errs QueryErrors
+	invalid := func(param, format string, args ...interface{}) {
+		errs = append(errs, &QueryParamError{Parameter: param, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		family := key
+		if i := strings.IndexByte(key, '['); i >= 0 {
+			family = key[:i]
+		}
+
+		switch {
+		case family == QueryParamFields:
+			if typ, ok := bracketedParam(key, QueryParamFields); !ok || typ == "" {
+				invalid(key, "The %s query parameter must name a resource type, e.g. fields[articles]", key)
+			}
+		case family == QueryParamPage:
+			if !queryPageParams[key] {
+				invalid(key, "The %s query parameter is not supported", key)
+			}
+		case family != "" && !queryFamilies[family] && strings.Trim(family, "abcdefghijklmnopqrstuvwxyz") == "":
+			invalid(key, "The %s query parameter is not supported", key)
+		}
+	}
+
+	for _, path := range q.Include {
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" {
+				invalid(QueryParamInclude, "The include path %s is not valid", path)
+				break
+			}
+		}
+	}
+
+	for _, name := range q.Page.invalid {
+		invalid(name, "The %s query parameter must be a non-negative integer", name)
+	}
+
+	if len(errs) > 0 {
+		return q, errs
+	}
+	return q, nil
+}
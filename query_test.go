@@ -0,0 +1,338 @@
+package jsonapi_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestParseFieldsets(t *testing.T) {
+	var tests = map[string]struct {
+		query    string
+		expected map[string][]string
+	}{
+		"no fields": {
+			query:    "include=author&page[limit]=10",
+			expected: map[string][]string{},
+		},
+		"single type": {
+			query:    "fields[articles]=title,body",
+			expected: map[string][]string{"articles": {"title", "body"}},
+		},
+		"several types": {
+			query: "fields[articles]=title&fields[people]=name",
+			expected: map[string][]string{
+				"articles": {"title"},
+				"people":   {"name"},
+			},
+		},
+		"empty value": {
+			query:    "fields[articles]=&fields[people]=name",
+			expected: map[string][]string{"articles": {}, "people": {"name"}},
+		},
+		"repeated type": {
+			query:    "fields[articles]=title&fields[articles]=body,title&fields[articles]=",
+			expected: map[string][]string{"articles": {"title", "body"}},
+		},
+		"repeated empty value": {
+			query:    "fields[articles]=&fields[articles]=",
+			expected: map[string][]string{"articles": {}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, jsonapi.ParseFieldsets(query))
+		})
+	}
+}
+
+func TestParseFieldsets_EmptyIsNotNil(t *testing.T) {
+	fieldsets := jsonapi.ParseFieldsets(url.Values{"fields[articles]": {""}})
+
+	fields, ok := fieldsets["articles"]
+	assert.True(t, ok)
+	assert.NotNil(t, fields)
+	assert.Empty(t, fields)
+}
+
+func TestApplyFieldset(t *testing.T) {
+	newArticle := func() *jsonapi.ResourceObj {
+		return &jsonapi.ResourceObj{
+			Type: "articles",
+			ID:   "1",
+			Attributes: map[string]interface{}{
+				"title": "JSON:API paints my bikeshed!",
+				"body":  "The shortest article. Ever.",
+			},
+			Relationships: map[string]interface{}{
+				"author": &jsonapi.RelationshipOneNode{Data: &jsonapi.ResourceObj{Type: "people", ID: "9"}},
+			},
+		}
+	}
+
+	article := newArticle()
+	jsonapi.ApplyFieldset(article, map[string][]string{"articles": {"title", "author"}})
+	assert.Equal(t, map[string]interface{}{"title": "JSON:API paints my bikeshed!"}, article.Attributes)
+	assert.Contains(t, article.Relationships, "author")
+
+	article = newArticle()
+	jsonapi.ApplyFieldset(article, map[string][]string{"people": {"name"}})
+	assert.Equal(t, newArticle(), article)
+
+	article = newArticle()
+	query, _ := url.ParseQuery("fields[articles]=")
+	jsonapi.ApplyFieldset(article, jsonapi.ParseFieldsets(query))
+	assert.Empty(t, article.Attributes)
+	assert.Empty(t, article.Relationships)
+	assert.Equal(t, "1", article.ID)
+}
+
+func TestParsePageParams(t *testing.T) {
+	var tests = map[string]struct {
+		query    string
+		expected jsonapi.PageParams
+	}{
+		"none": {
+			query:    "sort=-date",
+			expected: jsonapi.PageParams{},
+		},
+		"offset": {
+			query:    "page[limit]=20&page[offset]=40",
+			expected: jsonapi.PageParams{Limit: 20, Offset: 40},
+		},
+		"page number": {
+			query:    "page[number]=3&page[size]=25",
+			expected: jsonapi.PageParams{Number: 3, Size: 25},
+		},
+		"scalar page": {
+			query:    "page=2&per_page=10",
+			expected: jsonapi.PageParams{Number: 2, Size: 10},
+		},
+		"bracketed page preferred": {
+			query:    "page[number]=4&page=2",
+			expected: jsonapi.PageParams{Number: 4},
+		},
+		"cursor": {
+			query:    "page[cursor]=abc123&page[size]=10",
+			expected: jsonapi.PageParams{Cursor: "abc123", Size: 10},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			params := jsonapi.ParsePageParams(query)
+			assert.Equal(t, test.expected, params)
+			assert.NoError(t, params.Validate())
+		})
+	}
+}
+
+func TestParsePageParams_Invalid(t *testing.T) {
+	query, _ := url.ParseQuery("page[offset]=-10&page[limit]=abc")
+
+	params := jsonapi.ParsePageParams(query)
+	assert.Equal(t, int64(0), params.Offset)
+	assert.Equal(t, int64(0), params.Limit)
+	assert.EqualError(t, params.Validate(), "The page[limit] query parameter must be a non-negative integer")
+}
+
+func TestParseFilters(t *testing.T) {
+	var tests = map[string]struct {
+		query    string
+		expected map[string][]string
+	}{
+		"no filters": {
+			query:    "sort=-date&fields[articles]=title",
+			expected: map[string][]string{},
+		},
+		"single depth": {
+			query:    "filter[status]=published&filter[tag]=go&filter[tag]=api",
+			expected: map[string][]string{"status": {"published"}, "tag": {"go", "api"}},
+		},
+		"nested": {
+			query: "filter[author][id]=5&filter[author][name]=Ann&filter[comments][author][id]=9",
+			expected: map[string][]string{
+				"author.id":          {"5"},
+				"author.name":        {"Ann"},
+				"comments.author.id": {"9"},
+			},
+		},
+		"malformed": {
+			query:    "filter[]=1&filter[a]b]=2&filter[a][]=3&filter=4",
+			expected: map[string][]string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, jsonapi.ParseFilters(query))
+		})
+	}
+}
+
+func TestParseInclude(t *testing.T) {
+	var tests = map[string]struct {
+		query    string
+		expected []string
+	}{
+		"absent":   {query: "sort=title", expected: nil},
+		"empty":    {query: "include=", expected: []string{}},
+		"one path": {query: "include=author", expected: []string{"author"}},
+		"paths":    {query: "include=author,comments.author", expected: []string{"author", "comments.author"}},
+		"spaces":   {query: "include=author,%20comments%20.%20author%20,%20", expected: []string{"author", "comments.author"}},
+		"plus":     {query: "include=author,+comments", expected: []string{"author", "comments"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, jsonapi.ParseInclude(query))
+		})
+	}
+}
+
+func TestFilterIncludes(t *testing.T) {
+	var tests = map[string]struct {
+		include  []string
+		expected []string
+	}{
+		"absent keeps the default": {include: nil, expected: []string{"comments,1", "comments,2", "comments,3", "posts,1", "posts,2"}},
+		"empty removes all":        {include: []string{}, expected: []string{}},
+		"one relationship":         {include: []string{"current_post"}, expected: []string{"posts,1"}},
+		"nested path":              {include: []string{"current_post.comments"}, expected: []string{"comments,1", "comments,2", "posts,1"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{SortIncluded: true})
+			assert.NoError(t, err)
+
+			jsonapi.FilterIncludes(p, test.include)
+
+			keys := []string{}
+			for _, n := range p.(*jsonapi.OnePayload).Included {
+				keys = append(keys, n.Type+","+n.ID)
+			}
+			assert.Equal(t, test.expected, keys)
+		})
+	}
+}
+
+func TestApplyIncludeQuery(t *testing.T) {
+	var tests = map[string]struct {
+		query    string
+		expected []string
+	}{
+		"absent keeps the default": {query: "", expected: []string{"comments,1", "comments,2", "comments,3", "posts,1", "posts,2"}},
+		"empty removes all":        {query: "include=", expected: []string{}},
+		"subset":                   {query: "include=posts", expected: []string{"posts,1", "posts,2"}},
+		"subset of nested paths":   {query: "include=current_post.comments,unknown", expected: []string{"comments,1", "comments,2", "posts,1"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			assert.NoError(t, err)
+
+			p, err := jsonapi.MarshalWithOptions([]*Blog{testBlog()}, jsonapi.MarshalOptions{SortIncluded: true})
+			assert.NoError(t, err)
+			payload := p.(*jsonapi.ManyPayload)
+
+			jsonapi.ApplyIncludeQuery(payload, query)
+
+			keys := []string{}
+			for _, n := range payload.Included {
+				keys = append(keys, n.Type+","+n.ID)
+			}
+			assert.Equal(t, test.expected, keys)
+			assert.Contains(t, payload.Data[0].Relationships, "posts", "linkage is kept")
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	query, err := url.ParseQuery("sort=-created,title,,-")
+	assert.NoError(t, err)
+	assert.Equal(t, []jsonapi.SortField{
+		{Field: "created", Descending: true},
+		{Field: "title"},
+	}, jsonapi.ParseSort(query))
+
+	assert.Nil(t, jsonapi.ParseSort(url.Values{}))
+}
+
+func TestValidateSort(t *testing.T) {
+	allowed := []string{"created", "title"}
+
+	assert.NoError(t, jsonapi.ValidateSort([]jsonapi.SortField{{Field: "created", Descending: true}, {Field: "title"}}, allowed))
+	assert.NoError(t, jsonapi.ValidateSort(nil, allowed))
+
+	err := jsonapi.ValidateSort([]jsonapi.SortField{{Field: "title"}, {Field: "author.name"}}, allowed)
+	assert.EqualError(t, err, "The sort field author.name is not supported")
+}
+
+func TestParseQuery(t *testing.T) {
+	query, err := url.ParseQuery("include=author,%20comments.author&fields[articles]=title,body&fields[people]=name" +
+		"&filter[author][id]=5&sort=-created,title&page[limit]=10&page[offset]=20&customParam=1")
+	assert.NoError(t, err)
+
+	q, err := jsonapi.ParseQuery(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"author", "comments.author"}, q.Include)
+	assert.Equal(t, map[string][]string{"articles": {"title", "body"}, "people": {"name"}}, q.Fields)
+	assert.Equal(t, map[string][]string{"author.id": {"5"}}, q.Filters)
+	assert.Equal(t, []jsonapi.SortField{{Field: "created", Descending: true}, {Field: "title"}}, q.Sort)
+	assert.Equal(t, int64(10), q.Page.Limit)
+	assert.Equal(t, int64(20), q.Page.Offset)
+
+	q, err = jsonapi.ParseQuery(url.Values{})
+	assert.NoError(t, err)
+	assert.Nil(t, q.Include)
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	query, err := url.ParseQuery("include=comments..author&fields=title&page[limit]=-1&page[size]=x&page[foo]=1&bogus=1&sort=title")
+	assert.NoError(t, err)
+
+	q, err := jsonapi.ParseQuery(query)
+	assert.EqualError(t, err, "The bogus query parameter is not supported; "+
+		"The fields query parameter must name a resource type, e.g. fields[articles]; "+
+		"The page[foo] query parameter is not supported; "+
+		"The include path comments..author is not valid; "+
+		"The page[limit] query parameter must be a non-negative integer; "+
+		"The page[size] query parameter must be a non-negative integer")
+	assert.Equal(t, []jsonapi.SortField{{Field: "title"}}, q.Sort, "valid parameters are still parsed")
+
+	errorObjects := jsonapi.ErrorsFromError(err)
+	if assert.Len(t, errorObjects, 6) {
+		assert.Equal(t, "400", errorObjects[0].Status)
+		assert.Equal(t, "Bad Request", errorObjects[0].Title)
+		assert.Equal(t, "The bogus query parameter is not supported", errorObjects[0].Detail)
+	}
+
+	var paramErr *jsonapi.QueryParamError
+	if assert.True(t, errors.As(err.(jsonapi.QueryErrors)[1], &paramErr)) {
+		assert.Equal(t, "fields", paramErr.Parameter)
+	}
+}
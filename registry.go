@@ -0,0 +1,102 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]reflect.Type)
+
+	attributeDecoders = make(map[string]AttributeDecoder)
+)
+
+// AttributeDecoder decodes the raw "attributes" member of a resource, e.g. to
+// decrypt a field before it is unmarshaled into a model.
+type AttributeDecoder func(raw json.RawMessage) (map[string]interface{}, error)
+
+// RegisterType registers the struct type of model, a pointer to a struct with
+// a primary annotated field, under the JSON API type named in that annotation.
+// Registered types are used by UnmarshalPolymorphic to instantiate the right
+// struct for each resource.
+func RegisterType(model interface{}) error {
+	t := reflect.TypeOf(model)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+
+	typ, ok := primaryType(t.Elem())
+	if !ok {
+		return ErrBadJSONAPIStructTag
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[typ] = t
+
+	return nil
+}
+
+// RegisterAttributeDecoder registers decoder to decode the attributes of
+// every resource of the JSON API type typ, in place of encoding/json, whenever
+// a ResourceObj is decoded, so in every unmarshal function of this package. A
+// nil decoder removes the decoder of typ.
+func RegisterAttributeDecoder(typ string, decoder AttributeDecoder) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if decoder == nil {
+		delete(attributeDecoders, typ)
+		return
+	}
+	attributeDecoders[typ] = decoder
+}
+
+// attributeDecoder returns the AttributeDecoder registered for the JSON API
+// type typ, if any.
+func attributeDecoder(typ string) (AttributeDecoder, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	decoder, ok := attributeDecoders[typ]
+	return decoder, ok
+}
+
+// hasAttributeDecoders reports whether any AttributeDecoder is registered.
+func hasAttributeDecoders() bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return len(attributeDecoders) > 0
+}
+
+// registeredType returns the struct pointer type registered for the JSON API
+// type typ.
+func registeredType(typ string) (reflect.Type, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	t, ok := registry[typ]
+	if !ok {
+		return nil, fmt.Errorf("No type is registered for resources of type %#v", typ)
+	}
+
+	return t, nil
+}
+
+// primaryType returns the JSON API type named by the primary annotation of
+// the struct type t.
+func primaryType(t reflect.Type) (string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		args := strings.Split(t.Field(i).Tag.Get(annotationJSONAPI), annotationSeperator)
+		if len(args) > 1 && args[0] == annotationPrimary {
+			return args[1], true
+		}
+	}
+
+	return "", false
+}
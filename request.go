@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +31,10 @@ var (
 	ErrUnknownFieldNumberType = errors.New("the struct field was not of a known number type")
 	// ErrInvalidType is returned when the given type is incompatible with the expected type.
 	ErrInvalidType = errors.New("invalid type provided") // I wish we used punctuation.
+	// ErrInvalidLinkage is returned when a relationship document does not have
+	// a "data" member holding null, a resource identifier or an array of
+	// resource identifiers.
+	ErrInvalidLinkage = errors.New("data must be null, a resource identifier or an array of resource identifiers")
 
 )
 
@@ -90,12 +96,191 @@ func newErrUnsupportedPtrType(rf reflect.Value, t reflect.Type, structField refl
 //
 // model interface{} should be a pointer to a struct.
 func UnmarshalPayload(in io.Reader, model interface{}) error {
+	return UnmarshalPayloadWithOptions(in, model, UnmarshalOptions{})
+}
+
+// UnmarshalOptions configures the optional behaviour of
+// UnmarshalPayloadWithOptions and UnmarshalManyPayloadWithOptions. The zero
+// value unmarshals the same as UnmarshalPayload.
+type UnmarshalOptions struct {
+	// DedupeOnDecode collapses "included" resources sharing a type and id into
+	// the most complete of them, the one with the most attributes, rather than
+	// using the last of them. This makes consuming producers that emit
+	// duplicate included resources robust.
+	DedupeOnDecode bool
+
+	// AllowedTypes restricts the types of the resources in "data" and
+	// "included"; a resource of any other type returns an error wrapping
+	// ErrDisallowedType. Nil allows every type.
+	AllowedTypes map[string]bool
+
+	// DisallowUnknownFields rejects documents, and resource objects in their
+	// "data" and "included", with members the JSON API specification does not
+	// define, rather than ignoring them.
+	DisallowUnknownFields bool
+
+	// NumericStrings decodes the string attributes that hold a JSON number,
+	// e.g. "42" or "-1.5e3", as json.Number, for producers that quote their
+	// numbers. Struct fields of a string type still receive the original
+	// string, and strings such as "007", which are not JSON numbers, are left
+	// as they are.
+	NumericStrings bool
+
+	// IDMember names the member holding the id of resource objects and
+	// resource identifiers, e.g. "_id" or "uid", for producers that do not
+	// use "id". It is decoded into ResourceObj.ID; empty means "id".
+	IDMember string
+}
+
+// numericString matches the JSON number grammar.
+var numericString = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// decodeNumericStrings replaces the string attributes of the resources of p
+// that hold a JSON number with a json.Number, when NumericStrings is set.
+func (opts *UnmarshalOptions) decodeNumericStrings(p Payloader) {
+	if !opts.NumericStrings {
+		return
+	}
+
+	for _, n := range payloadResources(p) {
+		for name, value := range n.Attributes {
+			if s, ok := value.(string); ok && numericString.MatchString(s) {
+				n.Attributes[name] = json.Number(s)
+			}
+		}
+	}
+}
+
+// reader returns in, with the IDMember of every resource object and resource
+// identifier renamed to "id" when one is configured.
+func (opts *UnmarshalOptions) reader(in io.Reader) (io.Reader, error) {
+	if opts.IDMember == "" || opts.IDMember == "id" {
+		return in, nil
+	}
+
+	raw, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	for _, member := range []string{"data", "included"} {
+		if value, ok := doc[member]; ok {
+			if doc[member], err = renameIDMember(value, opts.IDMember, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(raw), nil
+}
+
+// renameIDMember renames the member of the resource object, or array of
+// resource objects, in raw to "id", along with that of the resource
+// identifiers in its relationships when resource is set.
+func renameIDMember(raw json.RawMessage, member string, resource bool) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return raw, nil
+	}
+
+	switch trimmed[0] {
+	case '[':
+		var objs []json.RawMessage
+		if err := json.Unmarshal(trimmed, &objs); err != nil {
+			return nil, err
+		}
+		for i, obj := range objs {
+			renamed, err := renameIDMember(obj, member, resource)
+			if err != nil {
+				return nil, err
+			}
+			objs[i] = renamed
+		}
+		return json.Marshal(objs)
+	case '{':
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &obj); err != nil {
+			return nil, err
+		}
+		if id, ok := obj[member]; ok {
+			if _, ok := obj["id"]; !ok {
+				obj["id"] = id
+			}
+			delete(obj, member)
+		}
+
+		if rels, ok := obj["relationships"]; ok && resource {
+			var relationships map[string]map[string]json.RawMessage
+			if err := json.Unmarshal(rels, &relationships); err != nil {
+				return nil, err
+			}
+			for _, relationship := range relationships {
+				if data, ok := relationship["data"]; ok {
+					renamed, err := renameIDMember(data, member, false)
+					if err != nil {
+						return nil, err
+					}
+					relationship["data"] = renamed
+				}
+			}
+			renamed, err := json.Marshal(relationships)
+			if err != nil {
+				return nil, err
+			}
+			obj["relationships"] = renamed
+		}
+		return json.Marshal(obj)
+	}
+
+	return raw, nil
+}
+
+// includedMap indexes included by type and id.
+func (opts *UnmarshalOptions) includedMap(included []*ResourceObj) map[string]*ResourceObj {
+	includedMap := make(map[string]*ResourceObj, len(included))
+	for _, n := range included {
+		key := fmt.Sprintf("%s,%s", n.Type, n.ID)
+		if existing, ok := includedMap[key]; ok && opts.DedupeOnDecode {
+			if len(n.Attributes) <= len(existing.Attributes) {
+				continue
+			}
+		}
+		includedMap[key] = n
+	}
+	return includedMap
+}
+
+// UnmarshalPayloadWithOptions does the same as UnmarshalPayload, configured
+// by opts.
+func UnmarshalPayloadWithOptions(in io.Reader, model interface{}, opts UnmarshalOptions) error {
+	in, err := opts.reader(in)
+	if err != nil {
+		return err
+	}
+
 	payload := new(OnePayload)
 	var duplicate bytes.Buffer
 	tee := io.TeeReader(in, &duplicate)
-	if err := json.NewDecoder(tee).Decode(payload); err != nil {
+	if err := decode(tee, payload); err != nil {
+		return err
+	}
+	if opts.DisallowUnknownFields {
+		if err := checkUnknownMembers(duplicate.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := checkAllowedTypes(opts.AllowedTypes, payload); err != nil {
 		return err
 	}
+	opts.decodeNumericStrings(payload)
 
 	nulls := make(map[string]interface{})
 	if err := unmarshalShadow(duplicate, nulls); err != nil {
@@ -103,11 +288,7 @@ func UnmarshalPayload(in io.Reader, model interface{}) error {
 	}
 
 	if payload.Included != nil {
-		includedMap := make(map[string]*ResourceObj)
-		for _, included := range payload.Included {
-			key := fmt.Sprintf("%s,%s", included.Type, included.ID)
-			includedMap[key] = included
-		}
+		includedMap := opts.includedMap(payload.Included)
 
 		return unmarshalNode(payload.Data, nulls, reflect.ValueOf(model), &includedMap)
 	}
@@ -117,21 +298,37 @@ func UnmarshalPayload(in io.Reader, model interface{}) error {
 // UnmarshalManyPayload converts an io into a set of struct instances using
 // jsonapi tags on the type's struct fields.
 func UnmarshalManyPayload(in io.Reader, t reflect.Type) ([]interface{}, error) {
-	payload := new(ManyPayload)
+	return UnmarshalManyPayloadWithOptions(in, t, UnmarshalOptions{})
+}
 
-	if err := json.NewDecoder(in).Decode(payload); err != nil {
+// UnmarshalManyPayloadWithOptions does the same as UnmarshalManyPayload,
+// configured by opts.
+func UnmarshalManyPayloadWithOptions(in io.Reader, t reflect.Type, opts UnmarshalOptions) ([]interface{}, error) {
+	in, err := opts.reader(in)
+	if err != nil {
 		return nil, err
 	}
 
-	models := []interface{}{}                // will be populated from the "data"
-	includedMap := map[string]*ResourceObj{} // will be populate from the "included"
+	payload := new(ManyPayload)
+	var duplicate bytes.Buffer
 
-	if payload.Included != nil {
-		for _, included := range payload.Included {
-			key := fmt.Sprintf("%s,%s", included.Type, included.ID)
-			includedMap[key] = included
+	if err := decode(io.TeeReader(in, &duplicate), payload); err != nil {
+		return nil, err
+	}
+	if opts.DisallowUnknownFields {
+		if err := checkUnknownMembers(duplicate.Bytes()); err != nil {
+			return nil, err
 		}
 	}
+	if err := checkAllowedTypes(opts.AllowedTypes, payload); err != nil {
+		return nil, err
+	}
+	opts.decodeNumericStrings(payload)
+
+	// The "data" is decoded by now, so its length, rather than a count in the
+	// meta that may describe the whole collection, sizes the models exactly
+	models := make([]interface{}, 0, len(payload.Data)) // will be populated from the "data"
+	includedMap := opts.includedMap(payload.Included)   // will be populate from the "included"
 
 	for _, data := range payload.Data {
 		model := reflect.New(t.Elem())
@@ -147,6 +344,89 @@ func UnmarshalManyPayload(in io.Reader, t reflect.Type) ([]interface{}, error) {
 	return models, nil
 }
 
+// UnmarshalPolymorphic converts an io into a set of struct instances, where
+// the "data" may hold resources of several types. The struct for each resource
+// is chosen from the types registered with RegisterType.
+func UnmarshalPolymorphic(in io.Reader) ([]interface{}, error) {
+	payload := new(ManyPayload)
+
+	if err := decode(in, payload); err != nil {
+		return nil, err
+	}
+
+	models := make([]interface{}, 0, len(payload.Data)) // will be populated from the "data"
+	includedMap := map[string]*ResourceObj{}            // will be populate from the "included"
+
+	for _, included := range payload.Included {
+		key := fmt.Sprintf("%s,%s", included.Type, included.ID)
+		includedMap[key] = included
+	}
+
+	for _, data := range payload.Data {
+		t, err := registeredType(data.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		model := reflect.New(t.Elem())
+		nulls := make(map[string]interface{})
+
+		if err := unmarshalNode(data, nulls, model, &includedMap); err != nil {
+			return nil, err
+		}
+		models = append(models, model.Interface())
+	}
+
+	return models, nil
+}
+
+// UnmarshalLinkage parses the body of a relationship modification request,
+// e.g. a PATCH to /posts/1/relationships/tags, into its resource identifiers.
+// The returned bool reports whether the body was a to-one linkage (null or a
+// single resource identifier) rather than a to-many linkage (an array). A null
+// to-one linkage, clearing the relationship, returns no identifiers.
+func UnmarshalLinkage(in io.Reader) ([]*ResourceObj, bool, error) {
+	var doc struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	if err := decode(in, &doc); err != nil {
+		return nil, false, err
+	}
+
+	data := bytes.TrimSpace(doc.Data)
+	if len(data) == 0 {
+		return nil, false, ErrInvalidLinkage
+	}
+
+	switch data[0] {
+	case 'n':
+		return []*ResourceObj{}, true, nil
+	case '{':
+		identifier := new(ResourceObj)
+		if err := json.Unmarshal(data, identifier); err != nil {
+			return nil, false, err
+		}
+		if identifier.Type == "" {
+			return nil, false, ErrInvalidLinkage
+		}
+		return []*ResourceObj{identifier}, true, nil
+	case '[':
+		identifiers := []*ResourceObj{}
+		if err := json.Unmarshal(data, &identifiers); err != nil {
+			return nil, false, err
+		}
+		for _, identifier := range identifiers {
+			if identifier == nil || identifier.Type == "" {
+				return nil, false, ErrInvalidLinkage
+			}
+		}
+		return identifiers, false, nil
+	}
+
+	return nil, false, ErrInvalidLinkage
+}
+
 func unmarshalShadow(payload bytes.Buffer, data map[string]interface{}) (err error) {
 	v := new(NulledPayload)
 	if err := json.Unmarshal(payload.Bytes(), v); err != nil {
@@ -168,6 +448,12 @@ func unmarshalNode(data *ResourceObj, nulls map[string]interface{}, model reflec
 		}
 	}()
 
+	// Reject relationships whose resource identifiers lack a type or id
+	// before any of them is resolved
+	if err := ValidateLinkage(data); err != nil {
+		return err
+	}
+
 	modelValue := model.Elem()
 	modelType := modelValue.Type()
 
@@ -223,6 +509,17 @@ func unmarshalNode(data *ResourceObj, nulls map[string]interface{}, model reflec
 				continue
 			}
 
+			// Handle composite keys, decoded with the DefaultIDCodec
+			if kind == reflect.Slice && fieldType.Type.Elem().Kind() == reflect.String {
+				parts := DefaultIDCodec.Decode(data.ID)
+				idValue := reflect.MakeSlice(fieldType.Type, len(parts), len(parts))
+				for i, part := range parts {
+					idValue.Index(i).SetString(part)
+				}
+				fieldValue.Set(idValue)
+				continue
+			}
+
 			// Value was not a string... only other supported type was a numeric,
 			// which would have been sent as a float value.
 			floatValue, err := strconv.ParseFloat(data.ID, 64)
@@ -439,6 +736,10 @@ func unmarshalAttribute(
 	//value = reflect.ValueOf(attribute)
 	fieldType := structField.Type
 
+	if n, ok := attribute.(json.Number); ok {
+		attribute = numberAttribute(n, fieldType)
+	}
+
 	value, err = handleField(attribute, args, fieldType, fieldValue)
 	switch {
 	case err == ErrInvalidType:
@@ -453,6 +754,21 @@ func unmarshalAttribute(
 	return
 }
 
+// numberAttribute returns the json.Number n, as decoded from a numeric string
+// with NumericStrings, as the original string for a string field and as a
+// float64, like any other JSON number, otherwise.
+func numberAttribute(n json.Number, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.String {
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	return n.String()
+}
+
 // handleField parses each individual field given its type and value. The method allows for recursion when unmarshalling
 // so we can traverse to primitive types.
 func handleField(
@@ -3,6 +3,7 @@ package jsonapi_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -609,6 +610,39 @@ func TestUnmarshal_nonNumericID(t *testing.T) {
 	}
 }
 
+type CompositeKey struct {
+	ID   []string `jsonapi:"primary,composites"`
+	Name string   `jsonapi:"attr,name"`
+}
+
+func TestUnmarshalCompositeID(t *testing.T) {
+	defer func(codec jsonapi.IDCodec) { jsonapi.DefaultIDCodec = codec }(jsonapi.DefaultIDCodec)
+	jsonapi.DefaultIDCodec = jsonapi.DelimitedIDCodec{Delimiter: ":"}
+
+	in := &CompositeKey{ID: []string{"tenant", "123"}, Name: "composite"}
+
+	out := bytes.NewBuffer(nil)
+	if err := jsonapi.MarshalPayload(out, in); err != nil {
+		t.Fatal(err)
+	}
+
+	var jsonData map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &jsonData); err != nil {
+		t.Fatal(err)
+	}
+	if id := jsonData["data"].(map[string]interface{})["id"]; id != "tenant:123" {
+		t.Fatalf("Was expecting the data.id to be `tenant:123`, got `%v`", id)
+	}
+
+	model := new(CompositeKey)
+	if err := jsonapi.UnmarshalPayload(out, model); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, model) {
+		t.Fatalf("Expected %#v, got %#v", in, model)
+	}
+}
+
 func TestUnmarshalSetsAttrs(t *testing.T) {
 	out, err := unmarshalSamplePayload()
 	if err != nil {
@@ -716,6 +750,20 @@ func TestUnmarshalRelationshipsWithoutIncluded(t *testing.T) {
 	}
 }
 
+func TestUnmarshalRelationshipsInvalidLinkage(t *testing.T) {
+	payload := `{"data": {"type": "posts", "id": "1", "relationships": {
+		"comments": {"data": [{"type": "comments", "id": "5"}, {"type": "comments"}]}
+	}}}`
+
+	err := jsonapi.UnmarshalPayload(strings.NewReader(payload), new(Post))
+	if err == nil {
+		t.Fatal("Was expecting an error for a resource identifier without an id")
+	}
+	if expected := "The comments relationship contains a resource identifier without an id"; err.Error() != expected {
+		t.Fatalf("Was expecting the error `%s`, got `%s`", expected, err)
+	}
+}
+
 func TestUnmarshalRelationships(t *testing.T) {
 	out, err := unmarshalSamplePayload()
 	if err != nil {
@@ -768,6 +816,45 @@ func TestUnmarshalNullRelationship(t *testing.T) {
 	}
 }
 
+func TestUnmarshalMetaOnlyRelationship(t *testing.T) {
+	sample := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "posts",
+			"id":   "1",
+			"attributes": map[string]interface{}{
+				"title": "World",
+			},
+			"relationships": map[string]interface{}{
+				"comments": map[string]interface{}{
+					"meta": map[string]interface{}{"count": 3},
+				},
+				"latest_comment": map[string]interface{}{
+					"meta": map[string]interface{}{"hidden": true},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(Post)
+	if err := jsonapi.UnmarshalPayload(bytes.NewReader(data), out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Title != "World" {
+		t.Fatalf("Was expecting the title to be unmarshalled, got %q", out.Title)
+	}
+	if len(out.Comments) != 0 {
+		t.Fatalf("Was expecting no comments, got %d", len(out.Comments))
+	}
+	if out.LatestComment != nil {
+		t.Fatalf("Latest Comment was not left nil")
+	}
+}
+
 func TestUnmarshalNullRelationshipInSlice(t *testing.T) {
 	sample := map[string]interface{}{
 		"data": map[string]interface{}{
@@ -960,6 +1047,80 @@ func TestUnmarshalManyPayload(t *testing.T) {
 	}
 }
 
+func TestUnmarshalPolymorphic(t *testing.T) {
+	for _, model := range []interface{}{new(Post), new(Comment)} {
+		if err := jsonapi.RegisterType(model); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sample := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"type": "posts",
+				"id":   "1",
+				"attributes": map[string]interface{}{
+					"title": "Post",
+				},
+			},
+			map[string]interface{}{
+				"type": "comments",
+				"id":   "2",
+				"attributes": map[string]interface{}{
+					"body": "Comment",
+				},
+			},
+			map[string]interface{}{
+				"type": "posts",
+				"id":   "3",
+				"attributes": map[string]interface{}{
+					"title": "Another Post",
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models, err := jsonapi.UnmarshalPolymorphic(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(models) != 3 {
+		t.Fatalf("Was expecting 3 models, got %d", len(models))
+	}
+	if post, ok := models[0].(*Post); !ok || post.ID != 1 || post.Title != "Post" {
+		t.Fatalf("Was expecting the first model to be Post 1, got %#v", models[0])
+	}
+	if comment, ok := models[1].(*Comment); !ok || comment.ID != 2 || comment.Body != "Comment" {
+		t.Fatalf("Was expecting the second model to be Comment 2, got %#v", models[1])
+	}
+	if post, ok := models[2].(*Post); !ok || post.ID != 3 {
+		t.Fatalf("Was expecting the third model to be Post 3, got %#v", models[2])
+	}
+}
+
+func TestUnmarshalPolymorphic_UnregisteredType(t *testing.T) {
+	in := strings.NewReader(`{"data": [{"type": "unregistered", "id": "1"}]}`)
+
+	if _, err := jsonapi.UnmarshalPolymorphic(in); err == nil {
+		t.Fatal("Was expecting an error for an unregistered type")
+	}
+}
+
+func TestRegisterType_Invalid(t *testing.T) {
+	if err := jsonapi.RegisterType(Post{}); err != jsonapi.ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+	}
+	if err := jsonapi.RegisterType(new(Team)); err != jsonapi.ErrBadJSONAPIStructTag {
+		t.Fatalf("Was expecting ErrBadJSONAPIStructTag, got %v", err)
+	}
+}
+
 func TestManyPayload_withLinks(t *testing.T) {
 	firstPageURL := "http://somesite.com/movies?page[limit]=50&page[offset]=50"
 	prevPageURL := "http://somesite.com/movies?page[limit]=50&page[offset]=0"
@@ -1411,3 +1572,320 @@ func TestJSONTypes(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUnmarshalLinkage(t *testing.T) {
+	var tests = map[string]struct {
+		body     string
+		expected []*jsonapi.ResourceObj
+		toOne    bool
+	}{
+		"null clears to-one": {
+			body:     `{"data": null}`,
+			expected: []*jsonapi.ResourceObj{},
+			toOne:    true,
+		},
+		"single identifier": {
+			body:     `{"data": {"type": "people", "id": "12"}}`,
+			expected: []*jsonapi.ResourceObj{{Type: "people", ID: "12"}},
+			toOne:    true,
+		},
+		"array of identifiers": {
+			body:     `{"data": [{"type": "tags", "id": "2"}, {"type": "tags", "id": "3"}]}`,
+			expected: []*jsonapi.ResourceObj{{Type: "tags", ID: "2"}, {Type: "tags", ID: "3"}},
+		},
+		"empty array clears to-many": {
+			body:     `{"data": []}`,
+			expected: []*jsonapi.ResourceObj{},
+		},
+		"identifier meta is kept": {
+			body: `{"data": [{"type": "tags", "id": "2", "meta": {"position": 1}}, {"type": "tags", "id": "3"}]}`,
+			expected: []*jsonapi.ResourceObj{
+				{Type: "tags", ID: "2", Meta: &jsonapi.Meta{"position": float64(1)}},
+				{Type: "tags", ID: "3"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			identifiers, toOne, err := jsonapi.UnmarshalLinkage(strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if toOne != test.toOne {
+				t.Fatalf("Expected to-one to be %v, got %v", test.toOne, toOne)
+			}
+			if !reflect.DeepEqual(identifiers, test.expected) {
+				t.Fatalf("Expected %#v, got %#v", test.expected, identifiers)
+			}
+		})
+	}
+}
+
+func TestUnmarshalLinkage_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{}`,
+		`{"data": "tags"}`,
+		`{"data": {"id": "1"}}`,
+		`{"data": [null]}`,
+	} {
+		if _, _, err := jsonapi.UnmarshalLinkage(strings.NewReader(body)); err != jsonapi.ErrInvalidLinkage {
+			t.Fatalf("Expected ErrInvalidLinkage for %s, got %v", body, err)
+		}
+	}
+}
+
+func TestUnmarshalPayloadWithOptions_DedupeOnDecode(t *testing.T) {
+	payload := func() io.Reader {
+		return strings.NewReader(`{
+			"data": {
+				"type": "posts",
+				"id": "1",
+				"attributes": {"title": "Dupes"},
+				"relationships": {
+					"comments": {"data": [{"type": "comments", "id": "1"}, {"type": "comments", "id": "2"}]}
+				}
+			},
+			"included": [
+				{"type": "comments", "id": "1", "attributes": {"body": "first", "post_id": 1}},
+				{"type": "comments", "id": "1", "attributes": {"body": "first"}},
+				{"type": "comments", "id": "2", "attributes": {"body": "second"}},
+				{"type": "comments", "id": "2", "attributes": {"body": "second", "post_id": 1}}
+			]
+		}`)
+	}
+
+	post := new(Post)
+	opts := jsonapi.UnmarshalOptions{DedupeOnDecode: true}
+	if err := jsonapi.UnmarshalPayloadWithOptions(payload(), post, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*Comment{
+		{ID: 1, PostID: 1, Body: "first"},
+		{ID: 2, PostID: 1, Body: "second"},
+	}
+	if !reflect.DeepEqual(post.Comments, expected) {
+		t.Fatalf("Expected the most complete comments %#v, got %#v", expected, post.Comments)
+	}
+
+	post = new(Post)
+	if err := jsonapi.UnmarshalPayload(payload(), post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Comments[0].PostID != 0 || post.Comments[1].PostID != 1 {
+		t.Fatalf("Expected the last of each duplicate without DedupeOnDecode, got %#v", post.Comments)
+	}
+}
+
+func TestUnmarshalPayloadWithOptions_AllowedTypes(t *testing.T) {
+	body := `{
+		"data": {"type": "posts", "id": "1", "attributes": {"title": "Allowed"}},
+		"included": [{"type": "comments", "id": "1", "attributes": {"body": "first"}}]
+	}`
+
+	post := new(Post)
+	opts := jsonapi.UnmarshalOptions{AllowedTypes: map[string]bool{"posts": true, "comments": true}}
+	if err := jsonapi.UnmarshalPayloadWithOptions(strings.NewReader(body), post, opts); err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Allowed" {
+		t.Fatalf("Expected the post to be unmarshaled, got %#v", post)
+	}
+
+	opts = jsonapi.UnmarshalOptions{AllowedTypes: map[string]bool{"posts": true}}
+	err := jsonapi.UnmarshalPayloadWithOptions(strings.NewReader(body), new(Post), opts)
+	if !errors.Is(err, jsonapi.ErrDisallowedType) {
+		t.Fatalf("Expected ErrDisallowedType for the included comment, got %v", err)
+	}
+
+	_, err = jsonapi.UnmarshalManyPayloadWithOptions(
+		strings.NewReader(`{"data": [{"type": "postss", "id": "1"}]}`), reflect.TypeOf(new(Post)), opts)
+	if !errors.Is(err, jsonapi.ErrDisallowedType) {
+		t.Fatalf("Expected ErrDisallowedType for the misspelled type, got %v", err)
+	}
+}
+
+func TestRegisterAttributeDecoder(t *testing.T) {
+	calls := 0
+	jsonapi.RegisterAttributeDecoder("posts", func(raw json.RawMessage) (map[string]interface{}, error) {
+		calls++
+		attributes := map[string]interface{}{}
+		if err := json.Unmarshal(raw, &attributes); err != nil {
+			return nil, err
+		}
+		if title, ok := attributes["title"].(string); ok {
+			attributes["title"] = strings.TrimPrefix(title, "encrypted:")
+		}
+		return attributes, nil
+	})
+	defer jsonapi.RegisterAttributeDecoder("posts", nil)
+
+	body := `{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"attributes": {"title": "encrypted:Secret", "body": "encrypted:Body"},
+			"relationships": {"comments": {"data": [{"type": "comments", "id": "1"}]}}
+		},
+		"included": [{"type": "comments", "id": "1", "attributes": {"body": "encrypted:Comment"}}]
+	}`
+
+	post := new(Post)
+	if err := jsonapi.UnmarshalPayload(strings.NewReader(body), post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Secret" || post.Body != "encrypted:Body" {
+		t.Fatalf("Expected the posts decoder to decode the title only, got %#v", post)
+	}
+	if post.Comments[0].Body != "encrypted:Comment" {
+		t.Fatalf("Expected comments to be decoded without the posts decoder, got %#v", post.Comments[0])
+	}
+	if calls != 1 {
+		t.Fatalf("Expected the decoder to run once, ran %d times", calls)
+	}
+
+	jsonapi.RegisterAttributeDecoder("posts", nil)
+	post = new(Post)
+	if err := jsonapi.UnmarshalPayload(strings.NewReader(body), post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "encrypted:Secret" {
+		t.Fatalf("Expected the removed decoder not to run, got %#v", post)
+	}
+}
+
+func TestUnmarshalPayloadWithOptions_DisallowUnknownFields(t *testing.T) {
+	var tests = map[string]struct {
+		body string
+		err  string
+	}{
+		"known members": {
+			body: `{"data": {"type": "posts", "id": "1", "attributes": {"title": "Strict"}, "meta": {}}, "jsonapi": {"version": "1.1"}}`,
+		},
+		"unknown top level member": {
+			body: `{"data": {"type": "posts", "id": "1"}, "extra": true}`,
+			err:  "The document has an unknown member extra",
+		},
+		"unknown resource member": {
+			body: `{"data": {"type": "posts", "id": "1", "title": "Misplaced"}}`,
+			err:  "The resource at data has an unknown member title",
+		},
+		"unknown included member": {
+			body: `{"data": {"type": "posts", "id": "1"}, "included": [{"type": "comments", "id": "1", "body": "x"}]}`,
+			err:  "The resource at included[0] has an unknown member body",
+		},
+	}
+
+	strict := jsonapi.NewUnmarshalOptions(jsonapi.WithDisallowUnknownFields())
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := jsonapi.UnmarshalPayloadWithOptions(strings.NewReader(test.body), new(Post), strict)
+			if test.err == "" && err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if test.err != "" && (err == nil || err.Error() != test.err) {
+				t.Fatalf("Expected %q, got %v", test.err, err)
+			}
+
+			if err := jsonapi.UnmarshalPayload(strings.NewReader(test.body), new(Post)); err != nil {
+				t.Fatalf("Expected the lenient mode to ignore unknown members, got %v", err)
+			}
+		})
+	}
+
+	_, err := jsonapi.UnmarshalManyPayloadWithOptions(
+		strings.NewReader(`{"data": [{"type": "posts", "id": "1"}, {"type": "posts", "id": "2", "body": "x"}]}`),
+		reflect.TypeOf(new(Post)), strict)
+	if err == nil || err.Error() != "The resource at data[1] has an unknown member body" {
+		t.Fatalf("Expected the unknown member of data[1], got %v", err)
+	}
+}
+
+func TestUnmarshalPayloadWithOptions_IDMember(t *testing.T) {
+	body := `{
+		"data": {
+			"type": "posts",
+			"_id": "1",
+			"attributes": {"title": "Almost JSON API"},
+			"relationships": {
+				"comments": {"data": [{"type": "comments", "_id": "2"}]},
+				"latest_comment": {"data": null}
+			}
+		},
+		"included": [{"type": "comments", "_id": "2", "attributes": {"body": "Sideloaded"}}]
+	}`
+
+	post := new(Post)
+	opts := jsonapi.UnmarshalOptions{IDMember: "_id"}
+	if err := jsonapi.UnmarshalPayloadWithOptions(strings.NewReader(body), post, opts); err != nil {
+		t.Fatal(err)
+	}
+	if post.ID != 1 {
+		t.Fatalf("Expected the id from _id, got %d", post.ID)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].ID != 2 || post.Comments[0].Body != "Sideloaded" {
+		t.Fatalf("Expected the included comment resolved by its _id, got %+v", post.Comments)
+	}
+
+	models, err := jsonapi.UnmarshalManyPayloadWithOptions(
+		strings.NewReader(`{"data": [{"type": "posts", "uid": "1"}, {"type": "posts", "uid": "2"}]}`),
+		reflect.TypeOf(new(Post)), jsonapi.UnmarshalOptions{IDMember: "uid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || models[1].(*Post).ID != 2 {
+		t.Fatalf("Expected the ids from uid, got %+v", models)
+	}
+}
+
+func TestUnmarshalPayloadWithOptions_NumericStrings(t *testing.T) {
+	body := `{"data": {"type": "posts", "id": "1", "attributes": {"blog_id": "7", "title": "42", "body": "007"}}}`
+
+	post := new(Post)
+	opts := jsonapi.UnmarshalOptions{NumericStrings: true}
+	if err := jsonapi.UnmarshalPayloadWithOptions(strings.NewReader(body), post, opts); err != nil {
+		t.Fatal(err)
+	}
+	if post.BlogID != 7 {
+		t.Fatalf("Expected the quoted blog_id decoded as a number, got %d", post.BlogID)
+	}
+	if post.Title != "42" || post.Body != "007" {
+		t.Fatalf("Expected string fields to keep their strings, got %q and %q", post.Title, post.Body)
+	}
+
+	if err := jsonapi.UnmarshalPayload(strings.NewReader(body), new(Post)); err == nil {
+		t.Fatal("Expected the quoted blog_id to be rejected without NumericStrings")
+	}
+
+	models, err := jsonapi.UnmarshalManyPayloadWithOptions(
+		strings.NewReader(`{"data": [{"type": "posts", "id": "1", "attributes": {"blog_id": 3}}, {"type": "posts", "id": "2", "attributes": {"blog_id": "-4"}}]}`),
+		reflect.TypeOf(new(Post)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if models[0].(*Post).BlogID != 3 || models[1].(*Post).BlogID != -4 {
+		t.Fatalf("Expected mixed number and string blog_ids, got %+v", models)
+	}
+}
+
+func BenchmarkUnmarshalManyPayload(b *testing.B) {
+	posts := make([]interface{}, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		posts = append(posts, &Post{ID: uint64(i + 1), Title: fmt.Sprintf("Post %d", i), Body: "Body"})
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := jsonapi.MarshalPayload(out, posts); err != nil {
+		b.Fatal(err)
+	}
+	payload := out.Bytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := jsonapi.UnmarshalManyPayload(bytes.NewReader(payload), reflect.TypeOf(new(Post))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
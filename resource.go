@@ -1,20 +1,37 @@
 package jsonapi
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/url"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Payloader is used to encapsulate the One and Many payload types
 type Payloader interface {
 	clearIncluded()
+	setLink(key string, link interface{})
+	setMeta(key string, value interface{})
 	AddPagination(paginator Paginator)
 }
 
+// JSONAPIObject is used to represent the top-level `jsonapi` object, where a
+// server declares the version of the specification it implements and the
+// extensions and profiles applied to the document.
+// http://jsonapi.org/format/#document-jsonapi-object
+type JSONAPIObject struct {
+	Version string   `json:"version,omitempty"`
+	Ext     []string `json:"ext,omitempty"`
+	Profile []string `json:"profile,omitempty"`
+	Meta    *Meta    `json:"meta,omitempty"`
+}
+
 // NulledPayload allows for raw message to inspect nulls
 type NulledPayload struct {
 	Data ResourceObjNulls `json:"data"`
@@ -27,12 +44,27 @@ type OnePayload struct {
 	Included []*ResourceObj `json:"included,omitempty"`
 	Links    *Links         `json:"links,omitempty"`
 	Meta     *Meta          `json:"meta,omitempty"`
+	JSONAPI  *JSONAPIObject `json:"jsonapi,omitempty"`
 }
 
 func (p *OnePayload) clearIncluded() {
 	p.Included = []*ResourceObj{}
 }
 
+func (p *OnePayload) setLink(key string, link interface{}) {
+	if p.Links == nil {
+		p.Links = &Links{}
+	}
+	(*p.Links)[key] = link
+}
+
+func (p *OnePayload) setMeta(key string, value interface{}) {
+	if p.Meta == nil {
+		p.Meta = &Meta{}
+	}
+	(*p.Meta)[key] = value
+}
+
 func (p *OnePayload) AddPagination(paginator Paginator) {
 
 }
@@ -44,14 +76,30 @@ type ManyPayload struct {
 	Included []*ResourceObj `json:"included,omitempty"`
 	Links    *Links         `json:"links,omitempty"`
 	Meta     *Meta          `json:"meta,omitempty"`
+	JSONAPI  *JSONAPIObject `json:"jsonapi,omitempty"`
 }
 
 func (p *ManyPayload) clearIncluded() {
 	p.Included = []*ResourceObj{}
 }
 
+func (p *ManyPayload) setLink(key string, link interface{}) {
+	if p.Links == nil {
+		p.Links = &Links{}
+	}
+	(*p.Links)[key] = link
+}
+
+func (p *ManyPayload) setMeta(key string, value interface{}) {
+	if p.Meta == nil {
+		p.Meta = &Meta{}
+	}
+	(*p.Meta)[key] = value
+}
+
 func (p *ManyPayload) AddPagination(paginator Paginator) {
-	p.Links = paginator.GeneratePagination()
+	links, results := WithPaginationMeta(paginator)
+	p.Links = links
 
 	meta := Meta{}
 	existingMeta := p.Meta
@@ -59,15 +107,69 @@ func (p *ManyPayload) AddPagination(paginator Paginator) {
 		meta = *existingMeta
 	}
 
-	results := &Meta{
-		"total": paginator.GetTotal(),
-	}
-
 	meta["results"] = results
 
 	p.Meta = &meta
 }
 
+// MergeManyPayloads merges payloads into a single compound document, e.g. to
+// stitch together the responses of several services. Data is concatenated in
+// order, Included is deduplicated by type and id, keeping the first
+// occurrence and dropping resources already present in Data, and Meta keys
+// are merged with later payloads taking precedence. Links are taken from the
+// first payload, as the links of the others do not apply to the merged
+// document.
+func MergeManyPayloads(payloads ...*ManyPayload) *ManyPayload {
+	merged := &ManyPayload{Data: []*ResourceObj{}}
+
+	seen := make(map[string]bool)
+	for _, p := range payloads {
+		if p == nil {
+			continue
+		}
+		for _, n := range p.Data {
+			seen[resourceKey(n)] = true
+		}
+		merged.Data = append(merged.Data, p.Data...)
+	}
+
+	var meta *Meta
+	for i, p := range payloads {
+		if p == nil {
+			continue
+		}
+
+		if i == 0 && p.Links != nil {
+			links := make(Links, len(*p.Links))
+			for k, v := range *p.Links {
+				links[k] = v
+			}
+			merged.Links = &links
+		}
+
+		if p.Meta != nil {
+			if meta == nil {
+				meta = &Meta{}
+			}
+			for k, v := range *p.Meta {
+				(*meta)[k] = v
+			}
+		}
+
+		for _, n := range p.Included {
+			key := resourceKey(n)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged.Included = append(merged.Included, n)
+		}
+	}
+	merged.Meta = meta
+
+	return merged
+}
+
 // ResourceObjNulls is used to represent a generic JSON API Resource with null fields
 type ResourceObjNulls struct {
 	Type       string                     `json:"type"`
@@ -76,6 +178,10 @@ type ResourceObjNulls struct {
 }
 
 // ResourceObj is used to represent a generic JSON API Resource
+//
+// Attributes, relationships, links and meta are maps. The default codec
+// writes their keys in sorted order; MarshalOptions.SortKeys guarantees it
+// for any codec.
 type ResourceObj struct {
 	Type          string                 `json:"type"`
 	ID            string                 `json:"id,omitempty"`
@@ -83,6 +189,181 @@ type ResourceObj struct {
 	Relationships map[string]interface{} `json:"relationships,omitempty"`
 	Links         *Links                 `json:"links,omitempty"`
 	Meta          *Meta                  `json:"meta,omitempty"`
+
+	// attributeOrder is the order of the attributes on the wire, recorded by
+	// UnmarshalOrderedPayload.
+	attributeOrder []string
+	// emitEmptyRelationships writes "relationships": {} when there are none,
+	// set by the EmitEmptyRelationships marshal option.
+	emitEmptyRelationships bool
+}
+
+// MarshalJSON encodes a resource object with DefaultCodec, with an empty
+// "relationships" object when it was marshaled with EmitEmptyRelationships and
+// has none. Big number attributes are written as exact decimal strings, as
+// for the attributes of a model.
+func (n *ResourceObj) MarshalJSON() ([]byte, error) {
+	type resourceObj ResourceObj
+	if attributes, ok := bigDecimalAttributes(n.Attributes); ok {
+		copied := *n
+		copied.Attributes = attributes
+		n = &copied
+	}
+	if !n.emitEmptyRelationships || len(n.Relationships) > 0 {
+		return DefaultCodec.Marshal((*resourceObj)(n))
+	}
+
+	return DefaultCodec.Marshal(struct {
+		*resourceObj
+		Relationships map[string]interface{} `json:"relationships"`
+	}{(*resourceObj)(n), map[string]interface{}{}})
+}
+
+// bigDecimalAttributes returns a copy of attributes with its big number
+// values replaced by their bigDecimal strings, and whether it has any.
+func bigDecimalAttributes(attributes map[string]interface{}) (map[string]interface{}, bool) {
+	var replaced map[string]interface{}
+	for name, value := range attributes {
+		decimal, ok := bigDecimal(value)
+		if !ok {
+			continue
+		}
+		if replaced == nil {
+			replaced = make(map[string]interface{}, len(attributes))
+			for k, v := range attributes {
+				replaced[k] = v
+			}
+		}
+		replaced[name] = decimal
+	}
+	return replaced, replaced != nil
+}
+
+// UnmarshalJSON decodes a resource object with DefaultCodec, with the
+// attributes decoded by the AttributeDecoder registered for its type, if any.
+func (n *ResourceObj) UnmarshalJSON(data []byte) error {
+	type resourceObj ResourceObj
+	if !hasAttributeDecoders() {
+		return DefaultCodec.Unmarshal(data, (*resourceObj)(n))
+	}
+
+	var shadow struct {
+		*resourceObj
+		Attributes json.RawMessage `json:"attributes,omitempty"`
+	}
+	shadow.resourceObj = (*resourceObj)(n)
+	if err := DefaultCodec.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	if decoder, ok := attributeDecoder(n.Type); ok && len(shadow.Attributes) > 0 {
+		attributes, err := decoder(shadow.Attributes)
+		if err != nil {
+			return err
+		}
+		n.Attributes = attributes
+		return nil
+	}
+
+	if len(shadow.Attributes) > 0 {
+		return DefaultCodec.Unmarshal(shadow.Attributes, &n.Attributes)
+	}
+	return nil
+}
+
+// SetID sets the resource's id from the parts of its primary key, encoded
+// with DefaultIDCodec.
+func (n *ResourceObj) SetID(parts ...string) {
+	n.ID = DefaultIDCodec.Encode(parts...)
+}
+
+// ToLinkage returns the resource identifier of the resource, for use as
+// resource linkage. Some extensions allow meta on individual identifiers,
+// so the resource's meta is kept when keepMeta is set.
+//
+// http://jsonapi.org/format/#document-resource-identifier-objects
+func (n *ResourceObj) ToLinkage(keepMeta bool) *ResourceObj {
+	linkage := &ResourceObj{Type: n.Type, ID: n.ID}
+	if keepMeta {
+		linkage.Meta = n.Meta
+	}
+	return linkage
+}
+
+// IDParts returns the parts of the resource's primary key, decoded from its
+// id with DefaultIDCodec.
+func (n *ResourceObj) IDParts() []string {
+	return DefaultIDCodec.Decode(n.ID)
+}
+
+// PruneNullAttributes removes the attributes of o whose value is nil, or a
+// nil pointer, map or slice, so that they are omitted rather than serialized
+// as null. The attributes named in keepNulls are kept, for an explicit null
+// that clears the attribute, e.g. in a PATCH request. A nil o is left alone.
+func PruneNullAttributes(o *ResourceObj, keepNulls ...string) {
+	if o == nil {
+		return
+	}
+
+	keep := make(map[string]bool, len(keepNulls))
+	for _, name := range keepNulls {
+		keep[name] = true
+	}
+
+	for name, value := range o.Attributes {
+		if !keep[name] && isNull(value) {
+			delete(o.Attributes, name)
+		}
+	}
+}
+
+// isNull reports whether v is serialized as null.
+func isNull(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// IDCodec encodes the parts of a composite primary key into a single resource
+// id, and decodes a resource id back into those parts.
+type IDCodec interface {
+	Encode(parts ...string) string
+	Decode(id string) []string
+}
+
+// DefaultIDCodec is the IDCodec used by SetID and IDParts, and when
+// marshaling or unmarshaling a []string primary field. It defaults to an
+// identity codec, where the id is the only part of the key.
+var DefaultIDCodec IDCodec = identityIDCodec{}
+
+type identityIDCodec struct{}
+
+func (identityIDCodec) Encode(parts ...string) string {
+	return strings.Join(parts, "")
+}
+
+func (identityIDCodec) Decode(id string) []string {
+	return []string{id}
+}
+
+// DelimitedIDCodec is an IDCodec that joins the parts of a composite key with
+// Delimiter, e.g. "tenant:123".
+type DelimitedIDCodec struct {
+	Delimiter string
+}
+
+func (c DelimitedIDCodec) Encode(parts ...string) string {
+	return strings.Join(parts, c.Delimiter)
+}
+
+func (c DelimitedIDCodec) Decode(id string) []string {
+	return strings.Split(id, c.Delimiter)
 }
 
 // RelationshipOneNode is used to represent a generic has one JSON API relation
@@ -100,10 +381,266 @@ type RelationshipManyNode struct {
 	Meta  *Meta          `json:"meta,omitempty"`
 }
 
+// NewRelationshipOne returns a to-one relationship holding the resource
+// identifier of typ and id, or null linkage when id is empty.
+func NewRelationshipOne(typ, id string) *RelationshipOneNode {
+	if id == "" {
+		return &RelationshipOneNode{}
+	}
+	return &RelationshipOneNode{Data: &ResourceObj{Type: typ, ID: id}}
+}
+
+// NewRelationshipMany returns a to-many relationship holding a resource
+// identifier for each type and id pair, in order.
+func NewRelationshipMany(pairs ...[2]string) *RelationshipManyNode {
+	data := make([]*ResourceObj, 0, len(pairs))
+	for _, pair := range pairs {
+		data = append(data, &ResourceObj{Type: pair[0], ID: pair[1]})
+	}
+	return &RelationshipManyNode{Data: data}
+}
+
+// SetRelationshipPagination sets the links of the paginated to-many
+// relationship node to the pagination links of paginator, and its meta
+// "count" to total, the size of the whole relationship, keeping any other
+// links and meta the node has.
+func SetRelationshipPagination(node *RelationshipManyNode, paginator Paginator, total int) {
+	if links := paginator.GeneratePagination(); links != nil {
+		if node.Links == nil {
+			node.Links = &Links{}
+		}
+		for k, v := range *links {
+			(*node.Links)[k] = v
+		}
+	}
+
+	if node.Meta == nil {
+		node.Meta = &Meta{}
+	}
+	(*node.Meta)["count"] = total
+}
+
+// DedupeLinkage removes the resource identifiers of the to-many relationship
+// node that repeat the type and id of an earlier one, as duplicate linkage is
+// invalid, keeping the identifiers in order. Null identifiers are removed too.
+func DedupeLinkage(node *RelationshipManyNode) {
+	seen := make(map[string]bool, len(node.Data))
+	data := node.Data[:0]
+	for _, n := range node.Data {
+		if n == nil {
+			continue
+		}
+		if key := resourceKey(n); !seen[key] {
+			seen[key] = true
+			data = append(data, n)
+		}
+	}
+	node.Data = data
+}
+
+// NextPageParam returns the value of the query parameter param, e.g.
+// page[offset] or page[cursor], in the next link of the paginated to-many
+// relationship, so that a client can continue paging through it. It reports
+// false on the last page, when there is no next link.
+func (n *RelationshipManyNode) NextPageParam(param string) (string, bool) {
+	if n.Links == nil {
+		return "", false
+	}
+	return ExtractPageParam(*n.Links, KeyNextPage, param)
+}
+
+// RelationshipMetaNode is used to represent a generic JSON API relation that
+// has no resource linkage, only meta and/or links, e.g. {"meta": {"count": 3}}
+type RelationshipMetaNode struct {
+	Links *Links `json:"links,omitempty"`
+	Meta  *Meta  `json:"meta,omitempty"`
+}
+
+// RelationshipData is used to represent the resource linkage of a relation
+// whose cardinality is not known ahead of decoding; it accepts null, a single
+// resource identifier or an array of them.
+type RelationshipData struct {
+	one    *ResourceObj
+	many   []*ResourceObj
+	isMany bool
+}
+
+// One returns the resource identifier of a to-one linkage; it is nil both for
+// a null linkage and for a to-many linkage.
+func (d *RelationshipData) One() *ResourceObj {
+	return d.one
+}
+
+// Many returns the resource identifiers of the linkage, whatever its
+// cardinality; a to-one linkage returns its identifier, if any, as the only
+// element.
+func (d *RelationshipData) Many() []*ResourceObj {
+	if d.isMany {
+		return d.many
+	}
+	if d.one == nil {
+		return []*ResourceObj{}
+	}
+	return []*ResourceObj{d.one}
+}
+
+// IsMany reports whether the linkage was decoded from an array.
+func (d *RelationshipData) IsMany() bool {
+	return d.isMany
+}
+
+func (d *RelationshipData) UnmarshalJSON(data []byte) error {
+	*d = RelationshipData{}
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		d.isMany = true
+		d.many = []*ResourceObj{}
+		return json.Unmarshal(data, &d.many)
+	}
+
+	return json.Unmarshal(data, &d.one)
+}
+
+func (d RelationshipData) MarshalJSON() ([]byte, error) {
+	if d.isMany {
+		return json.Marshal(d.many)
+	}
+	return json.Marshal(d.one)
+}
+
 // Links is used to represent a `links` object.
 // http://jsonapi.org/format/#document-links
+//
+// Links is a plain map of link names to links; meta describing the links as a
+// whole, such as pagination totals, belongs in a sibling Meta object (see
+// WithPaginationMeta).
 type Links map[string]interface{}
 
+// MarshalJSON encodes a links object with DefaultCodec, without its empty
+// string links, so that a link that was never set is not written as e.g.
+// "related": "". Null links are kept.
+func (l Links) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("null"), nil
+	}
+
+	links := make(map[string]interface{}, len(l))
+	for k, v := range l {
+		if href, ok := v.(string); ok && href == "" {
+			continue
+		}
+		links[k] = v
+	}
+	return DefaultCodec.Marshal(links)
+}
+
+// UnmarshalJSON decodes a links object with DefaultCodec, so that string
+// links remain strings and link objects become Link values, as accepted by
+// validate. A link object with members Link has no field for, such as the
+// rel, describedby, title, type and hreflang members of JSON:API 1.1, is
+// kept as a map[string]interface{} so that none of its members are lost.
+func (l *Links) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := DefaultCodec.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*l = nil
+		return nil
+	}
+
+	links := make(Links, len(raw))
+	for k, v := range raw {
+		v = bytes.TrimSpace(v)
+		switch {
+		case len(v) == 0 || string(v) == "null":
+			links[k] = nil
+		case v[0] == '{':
+			link, err := unmarshalLinkObject(v)
+			if err != nil {
+				return err
+			}
+			links[k] = link
+		default:
+			var value interface{}
+			if err := DefaultCodec.Unmarshal(v, &value); err != nil {
+				return err
+			}
+			links[k] = value
+		}
+	}
+
+	*l = links
+	return nil
+}
+
+// unmarshalLinkObject decodes a link object to a Link when it has no members
+// other than href and meta, and to a map[string]interface{} otherwise.
+func unmarshalLinkObject(data []byte) (interface{}, error) {
+	var members map[string]json.RawMessage
+	if err := DefaultCodec.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for member := range members {
+		if member != "href" && member != "meta" {
+			var link map[string]interface{}
+			if err := DefaultCodec.Unmarshal(data, &link); err != nil {
+				return nil, err
+			}
+			return link, nil
+		}
+	}
+
+	var link Link
+	if err := DefaultCodec.Unmarshal(data, &link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Map returns a copy of l with the URL of each link replaced by the result
+// of fn, e.g. to add a version prefix or sign the query of every link. String
+// links, Link objects and decoded link objects are transformed; the other
+// members of link objects are kept, and null links are left null.
+func (l Links) Map(fn func(key, href string) string) Links {
+	if l == nil {
+		return nil
+	}
+
+	mapped := make(Links, len(l))
+	for k, v := range l {
+		switch link := v.(type) {
+		case string:
+			mapped[k] = fn(k, link)
+		case Link:
+			link.Href = fn(k, link.Href)
+			mapped[k] = link
+		case *Link:
+			if link == nil {
+				mapped[k] = link
+				continue
+			}
+			copied := *link
+			copied.Href = fn(k, copied.Href)
+			mapped[k] = &copied
+		case map[string]interface{}:
+			copied := make(map[string]interface{}, len(link))
+			for member, value := range link {
+				copied[member] = value
+			}
+			if href, ok := link["href"].(string); ok {
+				copied["href"] = fn(k, href)
+			}
+			mapped[k] = copied
+		default:
+			mapped[k] = v
+		}
+	}
+
+	return mapped
+}
+
 func (l *Links) validate() (err error) {
 	// Each member of a links object is a “link”. A link MUST be represented as
 	// either:
@@ -112,11 +649,15 @@ func (l *Links) validate() (err error) {
 	//    - href: a string containing the link’s URL.
 	//    - meta: a meta object containing non-standard meta-information about the
 	//            link.
+	//    - rel, describedby, title, type and hreflang, as of JSON:API 1.1.
+	//  - null if the link does not exist.
 	for k, v := range *l {
 		_, isString := v.(string)
 		_, isLink := v.(Link)
+		_, isLinkPtr := v.(*Link)
+		_, isObject := v.(map[string]interface{})
 
-		if !(isString || isLink) {
+		if !(isString || isLink || isLinkPtr || isObject || v == nil) {
 			return fmt.Errorf(
 				"The %s member of the links object was not a string or link object",
 				k,
@@ -132,8 +673,32 @@ type Link struct {
 	Meta Meta   `json:"meta,omitempty"`
 }
 
+// linkHref returns the URL of a member of a links object, whether it is a
+// string, a Link or a decoded link object.
+func linkHref(link interface{}) (string, bool) {
+	switch l := link.(type) {
+	case string:
+		return l, true
+	case Link:
+		return l.Href, true
+	case *Link:
+		if l != nil {
+			return l.Href, true
+		}
+	case map[string]interface{}:
+		href, ok := l["href"].(string)
+		return href, ok
+	}
+
+	return "", false
+}
+
 // Linkable is used to include document links in response data
 // e.g. {"self": "http://example.com/posts/1"}
+//
+// A model that implements Linkable has the links it returns set on its
+// resource object, whether it is marshaled as primary data or included, so a
+// self link can be computed from the model's own fields.
 type Linkable interface {
 	JSONAPILinks() *Links
 }
@@ -147,8 +712,18 @@ type RelationshipLinkable interface {
 
 // Meta is used to represent a `meta` object.
 // http://jsonapi.org/format/#document-meta
+//
+// The default codec writes the keys of Meta in sorted order at every level,
+// including nested Meta and map[string]interface{} values; with another codec
+// MarshalOptions.SortKeys is needed for stable output.
 type Meta map[string]interface{}
 
+// SetDocumentMeta sets the key member of the top-level meta of p, e.g.
+// "copyright", initializing the meta when p has none.
+func SetDocumentMeta(p Payloader, key string, value interface{}) {
+	p.setMeta(key, value)
+}
+
 // Metable is used to include document meta in response data
 // e.g. {"foo": "bar"}
 type Metable interface {
@@ -156,6 +731,9 @@ type Metable interface {
 }
 
 // RelationshipMetable is used to include relationship meta in response data
+//
+// The meta it returns is set on every relationship node of the model,
+// including to-one relationships whose data is null.
 type RelationshipMetable interface {
 	// JSONRelationshipMeta will be invoked for each relationship with the corresponding relation name (e.g. `comments`)
 	JSONAPIRelationshipMeta(relation string) *Meta
@@ -167,60 +745,223 @@ type Paginator interface {
 	GetTotal() int64
 }
 
+// PageCounter is implemented by paginators that know how many pages the
+// result set spans.
+type PageCounter interface {
+	GetPages() int64
+}
+
+// WithPaginationMeta returns the links generated by paginator along with a
+// Meta describing the result set ("total", "pages" when paginator, or the
+// Paginator a MetaDecorator wraps, implements PageCounter, and any meta of a
+// paginator that implements Metable), so that both can be set on a payload
+// together.
+func WithPaginationMeta(paginator Paginator) (*Links, *Meta) {
+	meta := Meta{}
+
+	if metable, ok := paginator.(Metable); ok {
+		if extra := metable.JSONAPIMeta(); extra != nil {
+			for k, v := range *extra {
+				meta[k] = v
+			}
+		}
+	}
+
+	meta["total"] = paginator.GetTotal()
+
+	if counter, ok := pageCounter(paginator); ok {
+		meta["pages"] = counter.GetPages()
+	}
+
+	return paginator.GeneratePagination(), &meta
+}
+
+// pageCounter returns paginator, or the Paginator wrapped by a MetaDecorator,
+// as a PageCounter when it is one.
+func pageCounter(paginator Paginator) (PageCounter, bool) {
+	for {
+		decorator, ok := paginator.(*MetaDecorator)
+		if !ok {
+			break
+		}
+		paginator = decorator.Paginator
+	}
+
+	counter, ok := paginator.(PageCounter)
+	return counter, ok
+}
+
+// CountOnlyPayload returns the response to a count-only request, e.g. one
+// with page[limit]=0 (see OffsetPagination.CountOnly): an empty "data" array,
+// with the total, and the page count when paginator is a PageCounter, in the
+// meta.
+func CountOnlyPayload(paginator Paginator) *ManyPayload {
+	links, meta := WithPaginationMeta(paginator)
+	return &ManyPayload{Data: []*ResourceObj{}, Links: links, Meta: meta}
+}
+
+// generatedAtMeta returns the pagination meta stamping the time it was
+// generated, or nil when stamp is not set.
+func generatedAtMeta(stamp bool) *Meta {
+	if !stamp {
+		return nil
+	}
+	return &Meta{"generated_at": time.Now().UTC().Format(time.RFC3339)}
+}
+
+// MetaDecorator wraps a Paginator, keeping its links and total, and adds Meta
+// to the pagination meta, e.g. the remaining rate-limit quota of the client.
+type MetaDecorator struct {
+	Paginator
+	Meta Meta
+}
+
+// JSONAPIMeta returns the meta of the wrapped Paginator, when it implements
+// Metable, merged with Meta.
+func (d *MetaDecorator) JSONAPIMeta() *Meta {
+	meta := Meta{}
+	if metable, ok := d.Paginator.(Metable); ok {
+		if inner := metable.JSONAPIMeta(); inner != nil {
+			for k, v := range *inner {
+				meta[k] = v
+			}
+		}
+	}
+	for k, v := range d.Meta {
+		meta[k] = v
+	}
+	return &meta
+}
+
+// ExtractPageParam returns the value of the query parameter param, e.g.
+// page[offset], in the URL of the key link, e.g. next, of links. This allows a
+// client to continue paging through a result set.
+func ExtractPageParam(links Links, key, param string) (string, bool) {
+	href, ok := linkHref(links[key])
+	if !ok {
+		return "", false
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	values, ok := u.Query()[param]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+
+	return values[0], true
+}
+
 type OffsetPagination struct {
 	URL   string
 	Limit int64
 	Total int64
+
+	// EmitNullBoundaryLinks includes the first, prev, next and last keys in the
+	// generated links with a null value when they are not applicable, rather
+	// than omitting them.
+	EmitNullBoundaryLinks bool
+
+	// EmitSelfLink includes a self link to the current page, with the clamped
+	// limit and offset of the request, so clients can bookmark it.
+	EmitSelfLink bool
+
+	// StampGeneratedAt adds a "generated_at" RFC 3339 timestamp to the
+	// pagination meta, to help diagnose stale cached pagination.
+	StampGeneratedAt bool
+
+	// RelativeLinks strips the scheme and host of an absolute URL, so the
+	// generated links are path-relative whichever host the request used.
+	RelativeLinks bool
+
+	// MaxPageLinks caps the number of links returned by AllPageLinks; zero
+	// means no cap.
+	MaxPageLinks int64
+
+	// ParsedURL, when set, is used instead of URL by callers that already
+	// have the request URL parsed. The page parameters are then read from and
+	// written to its url.Values, so the generated links have their query
+	// encoded by url.Values.Encode, e.g. page%5Blimit%5D=100, with the
+	// parameters sorted by key.
+	ParsedURL *url.URL
+
+	// SigningSecret, when set, adds a sig query parameter to the generated
+	// links, an HMAC-SHA256 of their page parameters keyed by the secret, so
+	// that handlers can reject tampered offsets with VerifyPageSignature.
+	SigningSecret []byte
+
+	// maxLimit, baseURL and keyNamer are set by the PaginationOptions given
+	// to NewOffsetPagination.
+	maxLimit int64
+	baseURL  string
+	keyNamer func(key string) string
+}
+
+func (p *OffsetPagination) JSONAPIMeta() *Meta {
+	return generatedAtMeta(p.StampGeneratedAt)
 }
 
 func (p *OffsetPagination) GeneratePagination() *Links {
-	if p.Total < p.Limit { // no pagination needed
-		return nil
+	links := p.generatePagination()
+	if links == nil || p.keyNamer == nil {
+		return links
 	}
 
-	// initiate the URL - if the page offset and Limit have not been set or is devoid of all
-	// query parameters then initialising will make string replacement a simple operation
+	named := Links{}
+	for k, v := range *links {
+		named[p.keyNamer(k)] = v
+	}
+	return &named
+}
 
-	if !strings.Contains(p.URL, "page[limit]") {
-		p.appendToURL("page[limit]=" + strconv.FormatInt(p.Limit, 10))
+func (p *OffsetPagination) generatePagination() *Links {
+	if p.RelativeLinks {
+		p.URL = relativeURL(p.URL)
+		if p.ParsedURL != nil {
+			p.ParsedURL = relativeParsedURL(p.ParsedURL)
+		}
 	}
-	if !strings.Contains(p.URL, "page[offset]") {
-		p.appendToURL("page[offset]=0")
+
+	if p.CountOnly() || p.Total < p.pageLimit() { // no pagination needed
+		if p.EmitNullBoundaryLinks || p.EmitSelfLink {
+			links := Links{}
+			if p.EmitSelfLink {
+				if p.ParsedURL != nil {
+					links[KeySelfPage] = p.finishLink(p.ParsedURL.String())
+				} else {
+					links[KeySelfPage] = p.finishLink(p.URL)
+				}
+			}
+			if p.EmitNullBoundaryLinks {
+				p.addNullBoundaryLinks(links)
+			}
+			return &links
+		}
+		return nil
 	}
 
 	links := Links{}
-	limit := int64(math.Min(float64(getPageParam("Limit", p.URL)), float64(p.Limit)))
-	if limit == 0 {
-		limit = p.Limit
-	}
-	offset := int64(math.Max(float64(getPageParam("offset", p.URL)), float64(0)))
+	limit, offset := p.currentPage()
 
-	if offset > 0 {
-		firstUrl := p.URL
-		replaceParam(&firstUrl, `page[limit]`, strconv.FormatInt(limit, 10))
-		replaceParam(&firstUrl, `page[offset]`, strconv.FormatInt(0, 10))
-		links[KeyFirstPage] = firstUrl
+	if p.EmitSelfLink {
+		links[KeySelfPage] = p.pageLink(limit, offset)
 	}
 
-	if offset > limit {
-		prevUrl := p.URL
-		replaceParam(&prevUrl, `page[limit]`, strconv.FormatInt(limit, 10))
-		prevOffset := offset - limit
-		replaceParam(&prevUrl, `page[offset]`, strconv.FormatInt(prevOffset, 10))
-		links[KeyPreviousPage] = prevUrl
+	if offset > 0 {
+		links[KeyFirstPage] = p.pageLink(limit, 0)
+		prevOffset := int64(math.Max(float64(offset-limit), float64(0)))
+		links[KeyPreviousPage] = p.pageLink(limit, prevOffset)
 	}
 
 	if offset+limit < p.Total-limit {
-		nextUrl := p.URL
-		replaceParam(&nextUrl, `page[limit]`, strconv.FormatInt(limit, 10))
 		nextOffset := offset + limit
-		replaceParam(&nextUrl, `page[offset]`, strconv.FormatInt(nextOffset, 10))
-		links[KeyNextPage] = nextUrl
+		links[KeyNextPage] = p.pageLink(limit, nextOffset)
 	}
 
 	if offset+limit < p.Total {
-		lastUrl := p.URL
-		replaceParam(&lastUrl, `page[limit]`, strconv.FormatInt(limit, 10))
 		pages := p.Total / limit
 		if p.Total%limit > 0 {
 			pages += 1
@@ -231,20 +972,363 @@ func (p *OffsetPagination) GeneratePagination() *Links {
 		if lastOffset > p.Total {
 			lastOffset -= limit
 		}
-		replaceParam(&lastUrl, `page[offset]`, strconv.FormatInt(lastOffset, 10))
-		links[KeyLastPage] = lastUrl
+		links[KeyLastPage] = p.pageLink(limit, lastOffset)
+	}
+
+	if p.EmitNullBoundaryLinks {
+		p.addNullBoundaryLinks(links)
 	}
 
 	return &links
 }
 
+// CountOnly reports whether the request explicitly asked for page[limit]=0,
+// meaning it wants only the total of the result set, not any resources. Then
+// GeneratePagination returns no links to other pages, as there are none to
+// navigate, and AllPageLinks returns none; see CountOnlyPayload.
+func (p *OffsetPagination) CountOnly() bool {
+	if p.ParsedURL != nil {
+		values, ok := p.ParsedURL.Query()[QueryParamPageLimit]
+		if !ok || len(values) == 0 {
+			return false
+		}
+		limit, err := strconv.ParseInt(values[0], 10, 64)
+		return err == nil && limit == 0
+	}
+	return regexp.MustCompile(`(?:^|[?&])page\[limit\]=0+(?:&|#|$)`).MatchString(p.URL)
+}
+
+// pageLimit returns the limit of the requested page: its page[limit] up to
+// the cap, or Limit when it asks for none. The links and the page count are
+// all computed with it.
+func (p *OffsetPagination) pageLimit() int64 {
+	var requested int64
+	if p.ParsedURL != nil {
+		requested, _ = strconv.ParseInt(p.ParsedURL.Query().Get(QueryParamPageLimit), 10, 64)
+	} else {
+		// The requested page[limit] is honoured up to the cap; it used to be
+		// looked up as page[Limit], never matched, so links always used Limit.
+		requested = getPageParam("limit", p.URL)
+	}
+
+	limit := int64(math.Min(float64(requested), float64(p.limitCap())))
+	if limit <= 0 {
+		limit = p.Limit
+	}
+	return limit
+}
+
+// currentPage returns the clamped limit and offset of the requested page,
+// adding the page parameters to the URL when they are missing.
+func (p *OffsetPagination) currentPage() (limit, offset int64) {
+	if p.ParsedURL != nil {
+		query := p.ParsedURL.Query()
+		limit = p.pageLimit()
+		offset, _ = strconv.ParseInt(query.Get(QueryParamPageOffset), 10, 64)
+		offset = int64(math.Max(float64(offset), float64(0)))
+
+		return limit, offset
+	}
+
+	// initiate the URL - if the page offset and Limit have not been set or is devoid of all
+	// query parameters then initialising will make string replacement a simple operation
+
+	if !hasParam(p.URL, "page[limit]") {
+		p.appendToURL("page[limit]=" + strconv.FormatInt(p.Limit, 10))
+	}
+	if !hasParam(p.URL, "page[offset]") {
+		p.appendToURL("page[offset]=0")
+	}
+
+	limit = p.pageLimit()
+	offset = int64(math.Max(float64(getPageParam("offset", p.URL)), float64(0)))
+
+	return limit, offset
+}
+
+// limitCap returns the largest page[limit] a request may ask for: the max
+// limit when one was given to NewOffsetPagination, or else Limit.
+func (p *OffsetPagination) limitCap() int64 {
+	if p.maxLimit > 0 {
+		return p.maxLimit
+	}
+	return p.Limit
+}
+
+// finishLink returns the generated page link resolved against the base URL
+// given to NewOffsetPagination, when it is path-relative, and signed with
+// SigningSecret.
+func (p *OffsetPagination) finishLink(link string) string {
+	if p.baseURL != "" && strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//") {
+		link = strings.TrimSuffix(p.baseURL, "/") + link
+	}
+	return signPageLink(link, p.SigningSecret)
+}
+
+// pageLink returns the URL of the page at offset with limit resources. Given
+// a ParsedURL, the page parameters are set on its url.Values, so the query is
+// re-encoded rather than rewritten in place.
+func (p *OffsetPagination) pageLink(limit, offset int64) string {
+	if p.ParsedURL != nil {
+		u := *p.ParsedURL
+		query := u.Query()
+		query.Set(QueryParamPageLimit, strconv.FormatInt(limit, 10))
+		query.Set(QueryParamPageOffset, strconv.FormatInt(offset, 10))
+		u.RawQuery = query.Encode()
+		return p.finishLink(u.String())
+	}
+
+	pageUrl := p.URL
+	replaceParam(&pageUrl, `page[limit]`, strconv.FormatInt(limit, 10))
+	replaceParam(&pageUrl, `page[offset]`, strconv.FormatInt(offset, 10))
+	return p.finishLink(pageUrl)
+}
+
+// AllPageLinks returns a link to every page of the result set, keyed
+// "page_1", "page_2" and so on, for UIs that render a full list of pages.
+// When MaxPageLinks is set and there are more pages, only that many links are
+// returned, for the pages around the current one.
+func (p *OffsetPagination) AllPageLinks() Links {
+	links := Links{}
+	if p.Limit <= 0 || p.CountOnly() {
+		return links
+	}
+
+	if p.RelativeLinks {
+		p.URL = relativeURL(p.URL)
+		if p.ParsedURL != nil {
+			p.ParsedURL = relativeParsedURL(p.ParsedURL)
+		}
+	}
+
+	limit, offset := p.currentPage()
+	pages := p.Total / limit
+	if p.Total%limit > 0 {
+		pages += 1
+	}
+
+	first, last := int64(1), pages
+	if p.MaxPageLinks > 0 && pages > p.MaxPageLinks {
+		current := offset/limit + 1
+		first = current - p.MaxPageLinks/2
+		if first < 1 {
+			first = 1
+		}
+		if first > pages-p.MaxPageLinks+1 {
+			first = pages - p.MaxPageLinks + 1
+		}
+		last = first + p.MaxPageLinks - 1
+	}
+
+	for page := first; page <= last; page++ {
+		links["page_"+strconv.FormatInt(page, 10)] = p.pageLink(limit, (page-1)*limit)
+	}
+
+	return links
+}
+
+// PageValues returns the page[limit] and page[offset] query values of the
+// first, prev, next and last pages generated by GeneratePagination, keyed by
+// link name, for callers that build their own URLs. Pages that are not
+// applicable are absent.
+func (p *OffsetPagination) PageValues() map[string]url.Values {
+	values := make(map[string]url.Values)
+
+	links := p.GeneratePagination()
+	if links == nil {
+		return values
+	}
+
+	for key := range *links {
+		limit, ok := ExtractPageParam(*links, key, QueryParamPageLimit)
+		if !ok {
+			continue
+		}
+		offset, ok := ExtractPageParam(*links, key, QueryParamPageOffset)
+		if !ok {
+			continue
+		}
+
+		values[key] = url.Values{
+			QueryParamPageLimit:  {limit},
+			QueryParamPageOffset: {offset},
+		}
+	}
+
+	return values
+}
+
+// OffsetFromPageNumber converts the page[number] and page[size] of a page
+// based request into the equivalent offset and limit, for backends that
+// paginate by offset. Pages are numbered from 1; lower numbers are treated as
+// the first page.
+func OffsetFromPageNumber(number, size int64) (offset, limit int64) {
+	if number < 1 {
+		number = 1
+	}
+	return (number - 1) * size, size
+}
+
+// PageNumberFromOffset is the inverse of OffsetFromPageNumber; it returns the
+// page number containing offset, and the page size, for pages of limit
+// resources.
+func PageNumberFromOffset(offset, limit int64) (number, size int64) {
+	if limit <= 0 || offset < 0 {
+		return 1, limit
+	}
+	return offset/limit + 1, limit
+}
+
+// addNullBoundaryLinks sets any of the first, prev, next and last links that
+// were not generated to nil so they are serialized as null.
+func (p *OffsetPagination) addNullBoundaryLinks(links Links) {
+	for _, key := range []string{KeyFirstPage, KeyPreviousPage, KeyNextPage, KeyLastPage} {
+		if _, ok := links[key]; !ok {
+			links[key] = nil
+		}
+	}
+}
+
 func (p *OffsetPagination) GetTotal() int64 {
 	return p.Total
 }
 
+func (p *OffsetPagination) GetPages() int64 {
+	limit := p.pageLimit()
+	if limit <= 0 {
+		return 0
+	}
+
+	pages := p.Total / limit
+	if p.Total%limit > 0 {
+		pages += 1
+	}
+
+	return pages
+}
+
+// SimplePagePagination paginates with the scalar page and per_page query
+// parameters, e.g. "?page=3&per_page=20", used by APIs that predate the
+// JSON API page family. Pages are numbered from 1.
+type SimplePagePagination struct {
+	URL     string
+	PerPage int64
+	Total   int64
+
+	// StampGeneratedAt adds a "generated_at" RFC 3339 timestamp to the
+	// pagination meta.
+	StampGeneratedAt bool
+
+	// RelativeLinks strips the scheme and host of an absolute URL, so the
+	// generated links are path-relative.
+	RelativeLinks bool
+}
+
+func (p *SimplePagePagination) JSONAPIMeta() *Meta {
+	return generatedAtMeta(p.StampGeneratedAt)
+}
+
+func (p *SimplePagePagination) GeneratePagination() *Links {
+	if p.RelativeLinks {
+		p.URL = relativeURL(p.URL)
+	}
+
+	if p.PerPage <= 0 || p.Total < p.PerPage { // no pagination needed
+		return nil
+	}
+
+	if !hasParam(p.URL, QueryParamPerPage) {
+		p.appendToURL(QueryParamPerPage + "=" + strconv.FormatInt(p.PerPage, 10))
+	}
+	if !hasParam(p.URL, QueryParamPage) {
+		p.appendToURL(QueryParamPage + "=1")
+	}
+
+	perPage := int64(math.Min(float64(getScalarParam(QueryParamPerPage, p.URL)), float64(p.PerPage)))
+	if perPage <= 0 {
+		perPage = p.PerPage
+	}
+	page := int64(math.Max(float64(getScalarParam(QueryParamPage, p.URL)), float64(1)))
+
+	pages := p.Total / perPage
+	if p.Total%perPage > 0 {
+		pages += 1
+	}
+
+	links := Links{}
+	pageURL := func(n int64) string {
+		u := p.URL
+		replaceParam(&u, QueryParamPerPage, strconv.FormatInt(perPage, 10))
+		replaceParam(&u, QueryParamPage, strconv.FormatInt(n, 10))
+		return u
+	}
+
+	if page > 1 {
+		links[KeyFirstPage] = pageURL(1)
+		links[KeyPreviousPage] = pageURL(int64(math.Min(float64(page-1), float64(pages))))
+	}
+
+	if page < pages {
+		links[KeyNextPage] = pageURL(page + 1)
+		links[KeyLastPage] = pageURL(pages)
+	}
+
+	return &links
+}
+
+func (p *SimplePagePagination) GetTotal() int64 {
+	return p.Total
+}
+
+func (p *SimplePagePagination) GetPages() int64 {
+	if p.PerPage <= 0 {
+		return 0
+	}
+
+	pages := p.Total / p.PerPage
+	if p.Total%p.PerPage > 0 {
+		pages += 1
+	}
+
+	return pages
+}
+
+func (p *SimplePagePagination) appendToURL(param string) {
+	if !strings.Contains(p.URL, "?") {
+		p.URL += "?" + param
+	} else {
+		p.URL += "&" + param
+	}
+}
+
+// relativeURL returns the path, query and fragment of rawURL without its
+// scheme, credentials and host. URLs that are already relative, or that do not
+// parse, are returned unchanged.
+func relativeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme == "" && u.Host == "") {
+		return rawURL
+	}
+
+	return relativeParsedURL(u).String()
+}
+
+// relativeParsedURL returns a copy of u without its scheme, credentials and
+// host.
+func relativeParsedURL(u *url.URL) *url.URL {
+	relative := *u
+	relative.Scheme = ""
+	relative.User = nil
+	relative.Host = ""
+	if relative.Path == "" {
+		relative.Path = "/"
+	}
+	return &relative
+}
+
 func getPageParam(name, url string) int64 {
 	val := 0
-	valRe := regexp.MustCompile(fmt.Sprintf(`page\[%s\]=(\d+)`, name))
+	valRe := regexp.MustCompile(fmt.Sprintf(`(?:^|[?&])page\[%s\]=(\d+)`, name))
 	match := valRe.FindStringSubmatch(url)
 	if len(match) == 2 { // when we have found the \d portion
 		ql := match[1]
@@ -253,6 +1337,18 @@ func getPageParam(name, url string) int64 {
 	return int64(val)
 }
 
+// getScalarParam returns the numeric value of the scalar query parameter
+// name, e.g. "page=3", which the bracketed getPageParam does not match.
+func getScalarParam(name, url string) int64 {
+	val := 0
+	valRe := regexp.MustCompile(fmt.Sprintf(`(?:^|[?&])%s=(\d+)`, regexSafe(name)))
+	match := valRe.FindStringSubmatch(url)
+	if len(match) == 2 {
+		val, _ = strconv.Atoi(match[1])
+	}
+	return int64(val)
+}
+
 func replaceParam(url *string, param, value string) {
 	var sb strings.Builder
 	sb.WriteString(param)
@@ -260,13 +1356,21 @@ func replaceParam(url *string, param, value string) {
 	sb.WriteString(value)
 	newParam := sb.String()
 
-	seek := fmt.Sprintf(`%s=[^&]+`, regexSafe(param))
+	// only match whole query parameters so that other parameters sharing the
+	// same suffix, or repeated parameters such as filter[tag], are left intact
+	seek := fmt.Sprintf(`(^|[?&])%s=[^&]+`, regexSafe(param))
 	regex := regexp.MustCompile(seek)
-	match := regex.ReplaceAllString(*url, newParam)
+	match := regex.ReplaceAllString(*url, "${1}"+newParam)
 
 	*url = match
 }
 
+// hasParam reports whether the query string of url contains param.
+func hasParam(url, param string) bool {
+	seek := fmt.Sprintf(`(^|[?&])%s(=|&|$)`, regexSafe(param))
+	return regexp.MustCompile(seek).MatchString(url)
+}
+
 func regexSafe(in string) string {
 	chars := []string{"]", "^", "\\", "[", ".", "(", ")", "-"}
 	r := strings.Join(chars, "")
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
+	"io/ioutil"
+	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +30,19 @@ var (
 	// ErrUnexpectedType is returned when marshalling an interface; the interface
 	// had to be a pointer or a slice; otherwise this error is returned.
 	ErrUnexpectedType = errors.New("models should be a struct pointer or slice of struct pointers")
+	// ErrTooManyIncluded is returned when marshalling with a MaxIncluded
+	// option; the "included" array would have held more resources than allowed.
+	ErrTooManyIncluded = errors.New("included resources exceeded the MaxIncluded limit")
+	// ErrDisallowedType is returned when marshalling or unmarshalling with an
+	// AllowedTypes option; a resource had a type not in the set.
+	ErrDisallowedType = errors.New("resource type is not allowed")
+	// ErrNestedResource is returned when marshalling an attribute whose value
+	// is, or holds, a ResourceObj; related resources belong in relationships.
+	ErrNestedResource = errors.New("attributes must not hold resource objects, use a relationship instead")
+	// ErrReservedAttribute is returned when marshalling an attribute named
+	// "type" or "id", which the specification reserves for the resource
+	// object itself.
+	ErrReservedAttribute = errors.New("attributes must not be named type or id")
 )
 
 // MarshalPayload writes a jsonapi response for one or many records. The
@@ -63,18 +80,310 @@ var (
 //	 }
 //
 func MarshalPayload(w io.Writer, models interface{}) error {
-	payload, err := Marshal(models)
+	return MarshalPayloadWithOptions(w, models, MarshalOptions{})
+}
+
+// MarshalOptions configures the optional behaviour of MarshalWithOptions and
+// MarshalPayloadWithOptions. The zero value marshals the same as Marshal.
+type MarshalOptions struct {
+	// IncludeZeroValues marshals zero value attributes even when their field
+	// is tagged with omitempty.
+	IncludeZeroValues bool
+
+	// MaxIncluded limits the number of resources in the "included" array;
+	// zero means no limit. Exceeding the limit returns ErrTooManyIncluded,
+	// unless TruncateIncluded is set.
+	MaxIncluded int
+	// TruncateIncluded drops the resources beyond MaxIncluded from the
+	// "included" array, and adds a "warning" to the document meta, rather than
+	// returning an error.
+	TruncateIncluded bool
+
+	// StringifyMetaNumbers converts the numeric values of every meta object
+	// in the payload to strings, for clients that expect only string values.
+	// Meta added to the payload after marshaling, e.g. by AddPagination, is
+	// not converted.
+	StringifyMetaNumbers bool
+
+	// HTMLEscapeAttributes HTML-escapes string attribute values, as
+	// html/template does, for consumers that embed them in a page.
+	HTMLEscapeAttributes bool
+
+	// SingletonAsObject marshals a slice holding exactly one model as a
+	// OnePayload, with "data" as an object rather than a one element array,
+	// for clients of endpoints that are singular by nature.
+	//
+	// Use it with care: the shape of "data" then depends on the number of
+	// results, so a collection endpoint that happens to return one result is
+	// no longer a valid collection for clients that expect an array, and
+	// type assertions on the returned Payloader must handle both payloads.
+	SingletonAsObject bool
+
+	// SortIncluded orders the "included" array by type, then id, so that the
+	// document is deterministic, e.g. for caching. It is applied before
+	// MaxIncluded, so truncation is deterministic too.
+	SortIncluded bool
+
+	// AllowedTypes restricts the types of the resources in "data" and
+	// "included"; a resource of any other type returns an error wrapping
+	// ErrDisallowedType. Nil allows every type.
+	AllowedTypes map[string]bool
+
+	// EmitEmptyMeta writes "meta": {} at the top level of a document without
+	// meta, rather than omitting it, to signal that the meta section exists
+	// even when it is currently empty.
+	EmitEmptyMeta bool
+
+	// EmitEmptyRelationships writes "relationships": {} for the primary and
+	// included resources without relationships, rather than omitting it, to
+	// signal that the resource defines relationships even when none are
+	// populated.
+	EmitEmptyRelationships bool
+
+	// LaterIncludedMetaWins controls how the meta of a resource reached
+	// through several relationships, and included once, is merged: the keys of
+	// every instance are kept, and on a shared key the value of the first
+	// instance wins unless this is set, when the last instance wins.
+	LaterIncludedMetaWins bool
+
+	// MaxLinkage is the largest number of resource identifiers written as the
+	// data of a to-many relationship; zero means no limit. A relationship with
+	// more is written with only its links and meta, and its resources are not
+	// included, provided the model implements RelationshipLinkable and returns
+	// links for it, or RelationshipLinks is set and the resource has an id;
+	// otherwise its data is kept, as a relationship must hold at least one of
+	// data, links or meta.
+	MaxLinkage int
+
+	// RelationshipLinks, when set, generates the self and related links of
+	// every relationship in the document from the type and id of its resource
+	// and the relation name, keeping any links the relationship already has.
+	RelationshipLinks *RelationshipLinkOptions
+
+	// SortKeys writes the members of every object in the document, e.g. the
+	// attributes, relationships and meta of each resource, in sorted order
+	// whatever the DefaultCodec, so that the output is stable for golden
+	// tests and ETags. Arrays keep their order. It applies to
+	// MarshalPayloadWithOptions, which writes the document.
+	SortKeys bool
+}
+
+// apply applies the options that act on the marshaled payload as a whole.
+func (opts *MarshalOptions) apply(payload Payloader) error {
+	if err := checkAllowedTypes(opts.AllowedTypes, payload); err != nil {
+		return err
+	}
+
+	if opts.RelationshipLinks != nil {
+		applyRelationshipLinks(payload, opts.RelationshipLinks)
+	}
+
+	if opts.EmitEmptyRelationships {
+		for _, n := range payloadResources(payload) {
+			n.emitEmptyRelationships = true
+		}
+	}
+
+	switch p := payload.(type) {
+	case *OnePayload:
+		opts.sortIncluded(p.Included)
+		if err := opts.limitIncluded(&p.Included, &p.Meta); err != nil {
+			return err
+		}
+		if opts.StringifyMetaNumbers {
+			p.Meta = stringifyMeta(p.Meta)
+			stringifyResourceMeta(p.Data)
+			for _, n := range p.Included {
+				stringifyResourceMeta(n)
+			}
+		}
+		if opts.EmitEmptyMeta && p.Meta == nil {
+			p.Meta = &Meta{}
+		}
+	case *ManyPayload:
+		opts.sortIncluded(p.Included)
+		if err := opts.limitIncluded(&p.Included, &p.Meta); err != nil {
+			return err
+		}
+		if opts.StringifyMetaNumbers {
+			p.Meta = stringifyMeta(p.Meta)
+			for _, n := range p.Data {
+				stringifyResourceMeta(n)
+			}
+			for _, n := range p.Included {
+				stringifyResourceMeta(n)
+			}
+		}
+		if opts.EmitEmptyMeta && p.Meta == nil {
+			p.Meta = &Meta{}
+		}
+	}
+	return nil
+}
+
+// checkAllowedTypes returns an error for the first resource of p whose type
+// is not in allowed, unless allowed is nil.
+func checkAllowedTypes(allowed map[string]bool, p Payloader) error {
+	if allowed == nil {
+		return nil
+	}
+
+	for _, n := range payloadResources(p) {
+		if n != nil && !allowed[n.Type] {
+			return fmt.Errorf("%w: %s", ErrDisallowedType, n.Type)
+		}
+	}
+	return nil
+}
+
+func (opts *MarshalOptions) sortIncluded(included []*ResourceObj) {
+	if !opts.SortIncluded {
+		return
+	}
+
+	sort.SliceStable(included, func(i, j int) bool {
+		if included[i].Type != included[j].Type {
+			return included[i].Type < included[j].Type
+		}
+		return included[i].ID < included[j].ID
+	})
+}
+
+func (opts *MarshalOptions) limitIncluded(included *[]*ResourceObj, meta **Meta) error {
+	total := len(*included)
+	if opts.MaxIncluded <= 0 || total <= opts.MaxIncluded {
+		return nil
+	}
+
+	if !opts.TruncateIncluded {
+		return ErrTooManyIncluded
+	}
+
+	*included = (*included)[:opts.MaxIncluded]
+
+	// Copy the meta rather than modifying a map returned by a Metable
+	m := Meta{}
+	if *meta != nil {
+		for k, v := range **meta {
+			m[k] = v
+		}
+	}
+	m["warning"] = fmt.Sprintf(
+		"included resources truncated to %d of %d", opts.MaxIncluded, total,
+	)
+	*meta = &m
+
+	return nil
+}
+
+// stringifyResourceMeta converts the numeric meta values of n and of its
+// relationships to strings.
+func stringifyResourceMeta(n *ResourceObj) {
+	if n == nil {
+		return
+	}
+
+	n.Meta = stringifyMeta(n.Meta)
+	for _, rel := range n.Relationships {
+		switch r := rel.(type) {
+		case *RelationshipOneNode:
+			r.Meta = stringifyMeta(r.Meta)
+		case *RelationshipManyNode:
+			r.Meta = stringifyMeta(r.Meta)
+		case *RelationshipMetaNode:
+			r.Meta = stringifyMeta(r.Meta)
+		}
+	}
+}
+
+// stringifyMeta returns a copy of meta with its numeric values, including
+// those nested in objects and arrays, converted to strings.
+func stringifyMeta(meta *Meta) *Meta {
+	if meta == nil {
+		return nil
+	}
+
+	m := make(Meta, len(*meta))
+	for k, v := range *meta {
+		m[k] = stringifyNumbers(v)
+	}
+	return &m
+}
+
+func stringifyNumbers(v interface{}) interface{} {
+	switch value := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", value)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case json.Number:
+		return value.String()
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, nested := range value {
+			m[k] = stringifyNumbers(nested)
+		}
+		return m
+	case Meta:
+		return *stringifyMeta(&value)
+	case []interface{}:
+		a := make([]interface{}, len(value))
+		for i, nested := range value {
+			a[i] = stringifyNumbers(nested)
+		}
+		return a
+	}
+	return v
+}
+
+// MarshalPayloadWithOptions does the same as MarshalPayload, configured by
+// opts.
+func MarshalPayloadWithOptions(w io.Writer, models interface{}, opts MarshalOptions) error {
+	payload, err := MarshalWithOptions(models, opts)
 	if err != nil {
 		return err
 	}
 
-	return json.NewEncoder(w).Encode(payload)
+	if opts.SortKeys {
+		return encodeSorted(w, payload)
+	}
+	return encode(w, payload)
 }
 
 // Marshal does the same as MarshalPayload except it just returns the payload
 // and doesn't write out results. Useful if you use your own JSON rendering
 // library.
 func Marshal(models interface{}) (Payloader, error) {
+	return MarshalWithOptions(models, MarshalOptions{})
+}
+
+// MarshalWithOptions does the same as Marshal, configured by opts.
+func MarshalWithOptions(models interface{}, opts MarshalOptions) (Payloader, error) {
+	payload, err := marshalModels(models, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := opts.apply(payload); err != nil {
+		return nil, err
+	}
+
+	if many, ok := payload.(*ManyPayload); ok && opts.SingletonAsObject && len(many.Data) == 1 {
+		payload = &OnePayload{
+			Data:     many.Data[0],
+			Included: many.Included,
+			Links:    many.Links,
+			Meta:     many.Meta,
+			JSONAPI:  many.JSONAPI,
+		}
+	}
+
+	return payload, nil
+}
+
+func marshalModels(models interface{}, opts *MarshalOptions) (Payloader, error) {
 	switch vals := reflect.ValueOf(models); vals.Kind() {
 	case reflect.Slice:
 		m, err := convertToSliceInterface(&models)
@@ -82,7 +391,7 @@ func Marshal(models interface{}) (Payloader, error) {
 			return nil, err
 		}
 
-		payload, err := marshalMany(m)
+		payload, err := marshalMany(m, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -105,7 +414,7 @@ func Marshal(models interface{}) (Payloader, error) {
 		if reflect.Indirect(vals).Kind() != reflect.Struct {
 			return nil, ErrUnexpectedType
 		}
-		return marshalOne(models)
+		return marshalOne(models, opts)
 	default:
 		return nil, ErrUnexpectedType
 	}
@@ -122,7 +431,7 @@ func MarshalWithoutIncluded(models interface{}) (Payloader, error) {
 			return nil, err
 		}
 
-		payload, err := marshalMany(m)
+		payload, err := marshalMany(m, &MarshalOptions{})
 		if err != nil {
 			return nil, err
 		}
@@ -147,7 +456,7 @@ func MarshalWithoutIncluded(models interface{}) (Payloader, error) {
 		if reflect.Indirect(vals).Kind() != reflect.Struct {
 			return nil, ErrUnexpectedType
 		}
-		return marshalOne(models)
+		return marshalOne(models, &MarshalOptions{})
 	default:
 		return nil, ErrUnexpectedType
 	}
@@ -167,16 +476,155 @@ func MarshalPayloadWithoutIncluded(w io.Writer, model interface{}) error {
 	}
 	payload.clearIncluded()
 
-	return json.NewEncoder(w).Encode(payload)
+	return encode(w, payload)
+}
+
+// CompactDocument reduces p to a non-compound document, for clients that opt
+// out of included resources to save bandwidth: the "included" array is
+// cleared, and the relationships of the primary data keep only their
+// linkage, without links or meta. Relationships without linkage are removed.
+func CompactDocument(p Payloader) {
+	p.clearIncluded()
+
+	var data []*ResourceObj
+	switch payload := p.(type) {
+	case *OnePayload:
+		data = []*ResourceObj{payload.Data}
+	case *ManyPayload:
+		data = payload.Data
+	}
+
+	for _, n := range data {
+		if n == nil {
+			continue
+		}
+		for name, relationship := range n.Relationships {
+			switch rel := normalizeRelationship(relationship).(type) {
+			case *RelationshipOneNode:
+				n.Relationships[name] = &RelationshipOneNode{Data: rel.Data}
+			case *RelationshipManyNode:
+				n.Relationships[name] = &RelationshipManyNode{Data: rel.Data}
+			default:
+				delete(n.Relationships, name)
+			}
+		}
+	}
+}
+
+// MarshalValidate checks the payload p as MarshalPayload would write it, and
+// returns the first error found, without writing anything. It checks the
+// links of the document, of its resources and of their relationships, that
+// each resource has a type, no attributes named type or id (see
+// ErrReservedAttribute) and no resource objects in its attributes (see
+// ErrNestedResource), and that the payload can be encoded at all, which
+// makes it useful in tests and as a pre-flight check.
+func MarshalValidate(p Payloader) error {
+	var links *Links
+	switch payload := p.(type) {
+	case *OnePayload:
+		links = payload.Links
+	case *ManyPayload:
+		links = payload.Links
+	}
+	if links != nil {
+		if err := links.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, n := range payloadResources(p) {
+		if err := validateResourceLinks(n); err != nil {
+			return err
+		}
+	}
+
+	return encode(ioutil.Discard, p)
+}
+
+// bigDecimal returns the exact decimal string of a *big.Int or *big.Float
+// (or big.Int or big.Float) attribute, which would otherwise be written as a
+// number too large for most JSON clients to hold, or in exponent form.
+func bigDecimal(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case *big.Int:
+		if n != nil {
+			return n.String(), true
+		}
+	case big.Int:
+		return n.String(), true
+	case *big.Float:
+		if n != nil {
+			return n.Text('f', -1), true
+		}
+	case big.Float:
+		return n.Text('f', -1), true
+	}
+	return "", false
+}
+
+// reservedAttribute reports whether name may not be used for an attribute.
+func reservedAttribute(name string) bool {
+	return name == "type" || name == "id"
+}
+
+// validateResourceLinks checks that n has a type, no attributes named type or
+// id and no resource objects in its attributes, and validates its links and
+// those of its relationships, in name order.
+func validateResourceLinks(n *ResourceObj) error {
+	if n == nil {
+		return nil
+	}
+	if n.Type == "" {
+		return fmt.Errorf("The resource with id %q has no type", n.ID)
+	}
+	if n.Links != nil {
+		if err := n.Links.validate(); err != nil {
+			return err
+		}
+	}
+	for name, value := range n.Attributes {
+		if reservedAttribute(name) {
+			return fmt.Errorf("%w: %s", ErrReservedAttribute, name)
+		}
+		if holdsResourceObj(value) {
+			return fmt.Errorf("%w: %s", ErrNestedResource, name)
+		}
+	}
+
+	names := make([]string, 0, len(n.Relationships))
+	for name := range n.Relationships {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var links *Links
+		switch rel := n.Relationships[name].(type) {
+		case *RelationshipOneNode:
+			links = rel.Links
+		case *RelationshipManyNode:
+			links = rel.Links
+		case *RelationshipMetaNode:
+			links = rel.Links
+		}
+		if links == nil {
+			continue
+		}
+		if err := links.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // marshalOne does the same as MarshalOnePayload except it just returns the
 // payload and doesn't write out results. Useful is you use your JSON rendering
 // library.
-func marshalOne(model interface{}) (*OnePayload, error) {
+func marshalOne(model interface{}, opts *MarshalOptions) (*OnePayload, error) {
 	included := make(map[string]*ResourceObj)
 
-	rootNode, err := visitModelNode(model, &included, true)
+	rootNode, err := visitModelNode(model, &included, true, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -196,14 +644,14 @@ func marshalOne(model interface{}) (*OnePayload, error) {
 // marshalMany does the same as MarshalManyPayload except it just returns the
 // payload and doesn't write out results. Useful is you use your JSON rendering
 // library.
-func marshalMany(models []interface{}) (*ManyPayload, error) {
+func marshalMany(models []interface{}, opts *MarshalOptions) (*ManyPayload, error) {
 	payload := &ManyPayload{
 		Data: []*ResourceObj{},
 	}
 	included := map[string]*ResourceObj{}
 
 	for _, model := range models {
-		node, err := visitModelNode(model, &included, true)
+		node, err := visitModelNode(model, &included, true, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -230,18 +678,18 @@ func marshalMany(models []interface{}) (*ManyPayload, error) {
 //
 // model interface{} should be a pointer to a struct.
 func MarshalOnePayloadEmbedded(w io.Writer, model interface{}) error {
-	rootNode, err := visitModelNode(model, nil, false)
+	rootNode, err := visitModelNode(model, nil, false, &MarshalOptions{})
 	if err != nil {
 		return err
 	}
 
 	payload := &OnePayload{Data: rootNode}
 
-	return json.NewEncoder(w).Encode(payload)
+	return encode(w, payload)
 }
 
 func visitModelNode(model interface{}, included *map[string]*ResourceObj,
-	sideload bool) (*ResourceObj, error) {
+	sideload bool, opts *MarshalOptions) (*ResourceObj, error) {
 	node := new(ResourceObj)
 
 	var er error
@@ -253,10 +701,15 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 	modelValue := value.Elem()
 	modelType := value.Type().Elem()
 
-	for i := 0; i < modelValue.NumField(); i++ {
+	var embedded []reflect.Value
+
+	for _, i := range primaryFirst(modelType) {
 		structField := modelValue.Type().Field(i)
 		tag := structField.Tag.Get(annotationJSONAPI)
 		if tag == "" {
+			if base, ok := embeddedBase(structField, modelValue.Field(i)); ok {
+				embedded = append(embedded, base)
+			}
 			continue
 		}
 
@@ -309,6 +762,17 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 				node.ID = strconv.FormatUint(uint64(v.Interface().(uint32)), 10)
 			case reflect.Uint64:
 				node.ID = strconv.FormatUint(v.Interface().(uint64), 10)
+			case reflect.Slice:
+				// A composite key, encoded with the DefaultIDCodec
+				if v.Type().Elem().Kind() != reflect.String {
+					er = ErrBadJSONAPIID
+					break
+				}
+				parts := make([]string, v.Len())
+				for i := 0; i < v.Len(); i++ {
+					parts[i] = v.Index(i).String()
+				}
+				node.ID = DefaultIDCodec.Encode(parts...)
 			default:
 				// We had a JSON float (numeric), but our field was not one of the
 				// allowed numeric types
@@ -322,13 +786,18 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 			node.Type = args[1]
 
 		case annotation == annotationAttribute:
+			if reservedAttribute(args[1]) {
+				er = fmt.Errorf("%w: %s", ErrReservedAttribute, args[1])
+				break
+			}
+
 			var omitEmpty, iso8601 bool
 
 			if len(args) > 2 {
 				for _, arg := range args[2:] {
 					switch arg {
 					case annotationOmitEmpty:
-						omitEmpty = true
+						omitEmpty = !opts.IncludeZeroValues
 					case annotationISO8601:
 						iso8601 = true
 					}
@@ -383,7 +852,17 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 
 				strAttr, ok := fieldValue.Interface().(string)
 				if ok {
+					if opts.HTMLEscapeAttributes {
+						strAttr = html.EscapeString(strAttr)
+					}
 					node.Attributes[args[1]] = strAttr
+				} else if strPtr, ok := fieldValue.Interface().(*string); ok && strPtr != nil && opts.HTMLEscapeAttributes {
+					node.Attributes[args[1]] = html.EscapeString(*strPtr)
+				} else if decimal, ok := bigDecimal(fieldValue.Interface()); ok {
+					node.Attributes[args[1]] = decimal
+				} else if holdsResourceObj(fieldValue.Interface()) {
+					er = fmt.Errorf("%w: %s", ErrNestedResource, args[1])
+					break
 				} else {
 					node.Attributes[args[1]] = fieldValue.Interface()
 				}
@@ -447,6 +926,12 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 			var relLinks *Links
 			if linkableModel, ok := model.(RelationshipLinkable); ok {
 				relLinks = linkableModel.JSONAPIRelationshipLinks(args[1])
+				if relLinks != nil {
+					if err := relLinks.validate(); err != nil {
+						er = err
+						break
+					}
+				}
 			}
 
 			var relMeta *Meta
@@ -454,12 +939,24 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 				relMeta = metableModel.JSONAPIRelationshipMeta(args[1])
 			}
 
-			if isSlice {
+			// The links of RelationshipLinks are only generated once the
+			// payload is complete, but will be for a resource with an id,
+			// which primaryFirst has already set.
+			hasLinks := relLinks != nil && len(*relLinks) > 0 ||
+				opts.RelationshipLinks != nil && node.ID != ""
+			if isSlice && opts.MaxLinkage > 0 && fieldValue.Len() > opts.MaxLinkage && hasLinks {
+				// too many identifiers to inline, leave them to the links
+				node.Relationships[args[1]] = &RelationshipMetaNode{
+					Links: relLinks,
+					Meta:  relMeta,
+				}
+			} else if isSlice {
 				// to-many relationship
 				relationship, err := visitModelNodeRelationships(
 					fieldValue,
 					included,
 					sideload,
+					opts,
 				)
 				if err != nil {
 					er = err
@@ -471,7 +968,7 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 				if sideload {
 					shallowNodes := []*ResourceObj{}
 					for _, n := range relationship.Data {
-						appendIncluded(included, n)
+						appendIncluded(included, opts, n)
 						shallowNodes = append(shallowNodes, toShallowNode(n))
 					}
 
@@ -488,7 +985,11 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 
 				// Handle null relationship case
 				if fieldValue.IsNil() {
-					node.Relationships[args[1]] = &RelationshipOneNode{Data: nil}
+					node.Relationships[args[1]] = &RelationshipOneNode{
+						Data:  nil,
+						Links: relLinks,
+						Meta:  relMeta,
+					}
 					continue
 				}
 
@@ -496,6 +997,7 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 					fieldValue.Interface(),
 					included,
 					sideload,
+					opts,
 				)
 				if err != nil {
 					er = err
@@ -503,7 +1005,7 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 				}
 
 				if sideload {
-					appendIncluded(included, relationship)
+					appendIncluded(included, opts, relationship)
 					node.Relationships[args[1]] = &RelationshipOneNode{
 						Data:  toShallowNode(relationship),
 						Links: relLinks,
@@ -528,6 +1030,48 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 		return nil, er
 	}
 
+	// Promote the attributes and relationships of embedded base structs by
+	// the rules encoding/json uses for fields: the shallowest declaration of
+	// a name wins, and a name declared more than once at that depth is
+	// dropped. The winner is decided by declaration, so a winning field left
+	// out by omitempty does not let another struct's value through. A base is
+	// visited with its own included resources, so that only those reached
+	// through a promoted relationship are included.
+	if len(embedded) > 0 {
+		names := declaredNames(modelType, nil)
+		for _, base := range embedded {
+			baseIncluded := make(map[string]*ResourceObj)
+			baseNode, err := visitModelNode(base.Interface(), &baseIncluded, sideload, opts)
+			if err != nil {
+				return nil, err
+			}
+			baseNames := declaredNames(base.Type().Elem(), nil)
+			promoted := func(name string) bool {
+				winner, inBase := names[name], baseNames[name]
+				return !winner.ambiguous && winner.depth == inBase.depth+1
+			}
+			for name, value := range baseNode.Attributes {
+				if promoted(name) {
+					if node.Attributes == nil {
+						node.Attributes = make(map[string]interface{})
+					}
+					node.Attributes[name] = value
+				}
+			}
+			promotedRelationships := make(map[string]interface{})
+			for name, value := range baseNode.Relationships {
+				if promoted(name) {
+					if node.Relationships == nil {
+						node.Relationships = make(map[string]interface{})
+					}
+					node.Relationships[name] = value
+					promotedRelationships[name] = value
+				}
+			}
+			appendReached(included, opts, baseIncluded, promotedRelationships)
+		}
+	}
+
 	if linkableModel, isLinkable := model.(Linkable); isLinkable {
 		jl := linkableModel.JSONAPILinks()
 		if er := jl.validate(); er != nil {
@@ -548,6 +1092,149 @@ func visitModelNode(model interface{}, included *map[string]*ResourceObj,
 	return node, nil
 }
 
+// primaryFirst returns the indexes of the fields of the struct type t with
+// that of the primary field first, so that the id of a resource is known
+// when its relationships are visited, wherever the primary field is declared.
+func primaryFirst(t reflect.Type) []int {
+	fields := make([]int, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		args := strings.Split(t.Field(i).Tag.Get(annotationJSONAPI), annotationSeperator)
+		if args[0] == annotationPrimary {
+			fields = append([]int{i}, fields...)
+		} else {
+			fields = append(fields, i)
+		}
+	}
+	return fields
+}
+
+// declaration is the depth below a model at which an attribute or
+// relationship name is declared, 0 being the model's own fields, and whether
+// it is declared more than once at that depth.
+type declaration struct {
+	depth     int
+	ambiguous bool
+}
+
+// declaredNames returns the attribute and relationship names declared by the
+// struct type t and the base structs it embeds, however deep. seen holds the
+// types already on the path, so that a struct embedding a pointer to itself
+// is visited once.
+func declaredNames(t reflect.Type, seen map[reflect.Type]bool) map[string]declaration {
+	if seen[t] {
+		return nil
+	}
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	names := make(map[string]declaration)
+	var bases []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get(annotationJSONAPI)
+		if tag == "" {
+			if base, ok := embeddedBaseType(field); ok {
+				bases = append(bases, base)
+			}
+			continue
+		}
+
+		args := strings.Split(tag, annotationSeperator)
+		if len(args) > 1 && (args[0] == annotationAttribute || args[0] == annotationRelation) {
+			names[args[1]] = declaration{}
+		}
+	}
+
+	for _, base := range bases {
+		for name, d := range declaredNames(base, seen) {
+			d.depth++
+			current, ok := names[name]
+			switch {
+			case !ok || d.depth < current.depth:
+				names[name] = d
+			case d.depth == current.depth:
+				current.ambiguous = true
+				names[name] = current
+			}
+		}
+	}
+	return names
+}
+
+// embeddedBaseType returns the struct type embedded as field, when it is a
+// base struct as accepted by embeddedBase.
+func embeddedBaseType(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous || field.PkgPath != "" {
+		return nil, false
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	if _, ok := primaryType(t); ok {
+		return nil, false
+	}
+	return t, true
+}
+
+// embeddedBase returns a pointer to the struct embedded as field, when it is a
+// base struct whose tagged fields are promoted into the resource: an
+// exported, non-nil, struct or struct pointer without a primary annotation of
+// its own, which would make it a resource rather than part of one.
+func embeddedBase(field reflect.StructField, value reflect.Value) (reflect.Value, bool) {
+	if !field.Anonymous || field.PkgPath != "" {
+		return reflect.Value{}, false
+	}
+
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() || value.Elem().Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+	} else if value.Kind() == reflect.Struct {
+		value = value.Addr()
+	} else {
+		return reflect.Value{}, false
+	}
+
+	if _, ok := primaryType(value.Elem().Type()); ok {
+		return reflect.Value{}, false
+	}
+	return value, true
+}
+
+// holdsResourceObj reports whether the attribute value v is a ResourceObj,
+// or a generic array or object holding one, which would otherwise be
+// serialized as an attribute rather than a resource.
+func holdsResourceObj(v interface{}) bool {
+	switch value := v.(type) {
+	case *ResourceObj:
+		return value != nil
+	case ResourceObj, []*ResourceObj:
+		return true
+	case []interface{}:
+		for _, item := range value {
+			if holdsResourceObj(item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range value {
+			if holdsResourceObj(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func toShallowNode(node *ResourceObj) *ResourceObj {
 	return &ResourceObj{
 		ID:   node.ID,
@@ -556,13 +1243,13 @@ func toShallowNode(node *ResourceObj) *ResourceObj {
 }
 
 func visitModelNodeRelationships(models reflect.Value, included *map[string]*ResourceObj,
-	sideload bool) (*RelationshipManyNode, error) {
+	sideload bool, opts *MarshalOptions) (*RelationshipManyNode, error) {
 	nodes := []*ResourceObj{}
 
 	for i := 0; i < models.Len(); i++ {
 		n := models.Index(i).Interface()
 
-		node, err := visitModelNode(n, included, sideload)
+		node, err := visitModelNode(n, included, sideload, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -573,13 +1260,14 @@ func visitModelNodeRelationships(models reflect.Value, included *map[string]*Res
 	return &RelationshipManyNode{Data: nodes}, nil
 }
 
-func appendIncluded(m *map[string]*ResourceObj, nodes ...*ResourceObj) {
+func appendIncluded(m *map[string]*ResourceObj, opts *MarshalOptions, nodes ...*ResourceObj) {
 	included := *m
 
 	for _, n := range nodes {
 		k := fmt.Sprintf("%s,%s", n.Type, n.ID)
 
-		if _, hasNode := included[k]; hasNode {
+		if existing, hasNode := included[k]; hasNode {
+			existing.Meta = mergeMeta(existing.Meta, n.Meta, opts.LaterIncludedMetaWins)
 			continue
 		}
 
@@ -587,6 +1275,56 @@ func appendIncluded(m *map[string]*ResourceObj, nodes ...*ResourceObj) {
 	}
 }
 
+// appendReached appends to m the resources of candidates that relationships
+// reach, directly or through the relationships of other such resources.
+func appendReached(m *map[string]*ResourceObj, opts *MarshalOptions, candidates map[string]*ResourceObj, relationships map[string]interface{}) {
+	for _, relationship := range relationships {
+		var data []*ResourceObj
+		switch rel := relationship.(type) {
+		case *RelationshipOneNode:
+			if rel.Data != nil {
+				data = []*ResourceObj{rel.Data}
+			}
+		case *RelationshipManyNode:
+			data = rel.Data
+		}
+
+		for _, n := range data {
+			k := fmt.Sprintf("%s,%s", n.Type, n.ID)
+			reached, ok := candidates[k]
+			if !ok {
+				continue
+			}
+			delete(candidates, k)
+			appendIncluded(m, opts, reached)
+			appendReached(m, opts, candidates, reached.Relationships)
+		}
+	}
+}
+
+// mergeMeta returns a copy of first with the keys of second added, replacing
+// those first already has when secondWins is set, or first unchanged when
+// second adds nothing.
+func mergeMeta(first, second *Meta, secondWins bool) *Meta {
+	if second == nil || len(*second) == 0 || first == second {
+		return first
+	}
+	if first == nil {
+		first = &Meta{}
+	}
+
+	merged := make(Meta, len(*first)+len(*second))
+	for k, v := range *first {
+		merged[k] = v
+	}
+	for k, v := range *second {
+		if _, ok := merged[k]; !ok || secondWins {
+			merged[k] = v
+		}
+	}
+	return &merged
+}
+
 func nodeMapValues(m *map[string]*ResourceObj) []*ResourceObj {
 	mp := *m
 	nodes := make([]*ResourceObj, len(mp))
@@ -3,6 +3,8 @@ package jsonapi_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"math/big"
 	"reflect"
 	"sort"
 	"testing"
@@ -389,6 +391,110 @@ func TestOmitsEmptyAnnotation(t *testing.T) {
 	}
 }
 
+type ZeroValues struct {
+	ID     string `jsonapi:"primary,zero-values"`
+	Name   string `jsonapi:"attr,name,omitempty"`
+	Count  int    `jsonapi:"attr,count,omitempty"`
+	Active bool   `jsonapi:"attr,active,omitempty"`
+	Total  int    `jsonapi:"attr,total"`
+}
+
+func TestOmitsEmptyAnnotation_ZeroValues(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := jsonapi.MarshalPayload(out, &ZeroValues{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var jsonData map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &jsonData); err != nil {
+		t.Fatal(err)
+	}
+	attributes := jsonData["data"].(map[string]interface{})["attributes"].(map[string]interface{})
+
+	for _, key := range []string{"name", "count", "active"} {
+		if val, exists := attributes[key]; exists {
+			t.Fatalf("Was expecting the data.attributes.%s key/value to have been omitted - it was not and had a value of %v", key, val)
+		}
+	}
+	if val := attributes["total"]; val != float64(0) {
+		t.Fatalf("Was expecting the data.attributes.total to be 0, got %v", val)
+	}
+}
+
+func TestMarshalWithOptions_IncludeZeroValues(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	opts := jsonapi.MarshalOptions{IncludeZeroValues: true}
+	if err := jsonapi.MarshalPayloadWithOptions(out, &ZeroValues{ID: "1"}, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var jsonData map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &jsonData); err != nil {
+		t.Fatal(err)
+	}
+	attributes := jsonData["data"].(map[string]interface{})["attributes"].(map[string]interface{})
+
+	expected := map[string]interface{}{
+		"name":   "",
+		"count":  float64(0),
+		"active": false,
+		"total":  float64(0),
+	}
+	if !reflect.DeepEqual(expected, attributes) {
+		t.Fatalf("Expected attributes %#v, got %#v", expected, attributes)
+	}
+}
+
+func TestMarshalPayloadWithOptions_SortKeys(t *testing.T) {
+	defer func(codec jsonapi.Codec) { jsonapi.DefaultCodec = codec }(jsonapi.DefaultCodec)
+	jsonapi.DefaultCodec = reversingCodec{}
+
+	book := &Book{ID: 1, Author: "Author", ISBN: "123", Title: "Title", Pages: new(uint)}
+
+	out := bytes.NewBuffer(nil)
+	assert.NoError(t, jsonapi.MarshalPayloadWithOptions(out, book, jsonapi.MarshalOptions{}))
+	assert.False(t, keysSorted(out.Bytes()), "the codec does not sort keys")
+
+	for i := 0; i < 3; i++ {
+		sorted := bytes.NewBuffer(nil)
+		assert.NoError(t, jsonapi.MarshalPayloadWithOptions(sorted, book, jsonapi.MarshalOptions{SortKeys: true}))
+		assert.True(t, keysSorted(sorted.Bytes()), "attribute keys are sorted: %s", sorted)
+		assert.JSONEq(t, out.String(), sorted.String())
+		assert.Equal(t, byte('\n'), sorted.Bytes()[sorted.Len()-1])
+	}
+}
+
+func TestMarshalPayloadWithOptions_SortKeysRelationships(t *testing.T) {
+	defer func(codec jsonapi.Codec) { jsonapi.DefaultCodec = codec }(jsonapi.DefaultCodec)
+	jsonapi.DefaultCodec = reversingCodec{}
+
+	post := &Post{ID: 1, Comments: []*Comment{{ID: 2}}, LatestComment: &Comment{ID: 3}}
+
+	out := bytes.NewBuffer(nil)
+	assert.NoError(t, jsonapi.MarshalPayloadWithOptions(out, post, jsonapi.MarshalOptions{}))
+	assert.False(t, keysSorted(out.Bytes()), "the codec does not sort keys")
+
+	for i := 0; i < 3; i++ {
+		sorted := bytes.NewBuffer(nil)
+		assert.NoError(t, jsonapi.MarshalPayloadWithOptions(sorted, post, jsonapi.MarshalOptions{SortKeys: true}))
+		assert.True(t, keysSorted(sorted.Bytes()), "relationship keys are sorted: %s", sorted)
+		assert.Contains(t, sorted.String(), `"relationships":{"comments":{"data":[{"id":"2","type":"comments"}]},"latest_comment":{"data":{"id":"3","type":"comments"}}}`)
+	}
+}
+
+func TestMarshalPayloadWithOptions_SortKeysNestedMeta(t *testing.T) {
+	defer func(codec jsonapi.Codec) { jsonapi.DefaultCodec = codec }(jsonapi.DefaultCodec)
+	jsonapi.DefaultCodec = reversingCodec{}
+
+	// Blog has document meta and deeply nested relationship meta
+	blog := testBlog()
+
+	out := bytes.NewBuffer(nil)
+	assert.NoError(t, jsonapi.MarshalPayloadWithOptions(out, blog, jsonapi.MarshalOptions{SortKeys: true}))
+	assert.True(t, keysSorted(out.Bytes()), "nested meta keys are sorted: %s", out)
+	assert.Contains(t, out.String(), `"meta":{"this":{"can":{"go":["as","deep",{"as":"required"}]}}}`)
+}
+
 func TestHasPrimaryAnnotation(t *testing.T) {
 	testModel := &Blog{
 		ID:        5,
@@ -565,29 +671,20 @@ func TestSupportsLinkable(t *testing.T) {
 	if !hasComments {
 		t.Fatal("expect 'comments' to be present")
 	}
-	commentsMap, isMap := comments.(map[string]interface{})
-	if !isMap {
-		t.Fatal("Expected 'comments' to contain a map")
+	commentsLink, isLink := comments.(jsonapi.Link)
+	if !isLink {
+		t.Fatal("Expected 'comments' to contain a Link")
 	}
 
-	commentsHref, hasHref := commentsMap["href"]
-	if !hasHref {
+	if commentsLink.Href == "" {
 		t.Fatal("Expect 'comments' to contain an 'href' key/value")
 	}
-	if _, isString := commentsHref.(string); !isString {
-		t.Fatal("Expected 'href' to contain a string")
-	}
 
-	commentsMeta, hasMeta := commentsMap["meta"]
-	if !hasMeta {
+	if commentsLink.Meta == nil {
 		t.Fatal("Expect 'comments' to contain a 'meta' key/value")
 	}
-	commentsMetaMap, isMap := commentsMeta.(map[string]interface{})
-	if !isMap {
-		t.Fatal("Expected 'comments' to contain a map")
-	}
 
-	commentsMetaObject := jsonapi.Meta(commentsMetaMap)
+	commentsMetaObject := commentsLink.Meta
 	countsMap, isMap := commentsMetaObject["counts"].(map[string]interface{})
 	if !isMap {
 		t.Fatal("Expected 'counts' to contain a map")
@@ -611,6 +708,15 @@ func TestInvalidLinkable(t *testing.T) {
 	}
 }
 
+func TestInvalidRelationshipLinkable(t *testing.T) {
+	testModel := &BadRelationshipLinks{ID: 5}
+
+	out := bytes.NewBuffer(nil)
+	if err := jsonapi.MarshalPayload(out, testModel); err == nil {
+		t.Fatal("Was expecting an error")
+	}
+}
+
 func TestSupportsMetable(t *testing.T) {
 	testModel := &Blog{
 		ID:        5,
@@ -703,6 +809,48 @@ func TestNoRelations(t *testing.T) {
 	}
 }
 
+func TestMarshalWithOptions_MaxIncluded(t *testing.T) {
+	// testBlog sideloads 2 posts and 3 comments
+	if _, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{MaxIncluded: 3}); err != jsonapi.ErrTooManyIncluded {
+		t.Fatalf("Was expecting ErrTooManyIncluded, got %v", err)
+	}
+
+	p, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{MaxIncluded: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := p.(*jsonapi.OnePayload)
+	if e, a := 5, len(payload.Included); e != a {
+		t.Fatalf("Was expecting %d included resources, got %d", e, a)
+	}
+	if payload.Meta != nil {
+		t.Fatalf("Was not expecting any meta, got %v", *payload.Meta)
+	}
+}
+
+func TestMarshalWithOptions_TruncateIncluded(t *testing.T) {
+	opts := jsonapi.MarshalOptions{MaxIncluded: 3, TruncateIncluded: true}
+
+	p, err := jsonapi.MarshalWithOptions(testBlog(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := p.(*jsonapi.OnePayload)
+	if e, a := 3, len(payload.Included); e != a {
+		t.Fatalf("Was expecting %d included resources, got %d", e, a)
+	}
+	expected := &jsonapi.Meta{"warning": "included resources truncated to 3 of 5"}
+	assert.Equal(t, expected, payload.Meta)
+
+	p, err = jsonapi.MarshalWithOptions([]*Blog{testBlog()}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 3, len(p.(*jsonapi.ManyPayload).Included); e != a {
+		t.Fatalf("Was expecting %d included resources, got %d", e, a)
+	}
+}
+
 func TestMarshalPayloadWithoutIncluded(t *testing.T) {
 	data := &Post{
 		ID:     1,
@@ -1034,6 +1182,498 @@ func TestAddPagination(t *testing.T) {
 		"first": "/?page[offset]=0&page[limit]=100",
 		"last":  "/?page[offset]=500&page[limit]=100",
 		"next":  "/?page[offset]=200&page[limit]=100",
+		"prev":  "/?page[offset]=0&page[limit]=100",
 	}
 	assert.Equal(t, expected, payload.Links)
 }
+
+func TestMarshalWithOptions_StringifyMetaNumbers(t *testing.T) {
+	p, err := jsonapi.MarshalWithOptions(&Counted{ID: "1"}, jsonapi.MarshalOptions{StringifyMetaNumbers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(p.(*jsonapi.OnePayload).Data.Meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{
+		"total": "334",
+		"ratio": "0.25",
+		"label": "counted",
+		"nested": {"pages": "34"},
+		"samples": ["1", "two"]
+	}`, string(out))
+
+	p, err = jsonapi.Marshal(&Counted{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 334, (*p.(*jsonapi.OnePayload).Data.Meta)["total"])
+}
+
+func TestMarshalWithOptions_HTMLEscapeAttributes(t *testing.T) {
+	body := `<script>alert("hi")</script>`
+	post := func() *Post {
+		return &Post{ID: 1, Title: "Tom & Jerry", Body: body}
+	}
+
+	p, err := jsonapi.MarshalWithOptions(post(), jsonapi.MarshalOptions{HTMLEscapeAttributes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := p.(*jsonapi.OnePayload).Data.Attributes
+	assert.Equal(t, "&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt;", attrs["body"])
+	assert.Equal(t, "Tom &amp; Jerry", attrs["title"])
+
+	p, err = jsonapi.Marshal(post())
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs = p.(*jsonapi.OnePayload).Data.Attributes
+	assert.Equal(t, body, attrs["body"])
+	assert.Equal(t, "Tom & Jerry", attrs["title"])
+}
+
+func TestMarshalWithOptions_SingletonAsObject(t *testing.T) {
+	opts := jsonapi.MarshalOptions{SingletonAsObject: true}
+
+	p, err := jsonapi.MarshalWithOptions([]*Blog{testBlog()}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one, ok := p.(*jsonapi.OnePayload)
+	if !ok {
+		t.Fatalf("Expected a OnePayload for a single result, got %T", p)
+	}
+	assert.Equal(t, "blogs", one.Data.Type)
+	assert.Len(t, one.Included, 5)
+
+	p, err = jsonapi.MarshalWithOptions([]*Blog{testBlog(), testBlog()}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*jsonapi.ManyPayload); !ok {
+		t.Fatalf("Expected a ManyPayload for several results, got %T", p)
+	}
+
+	p, err = jsonapi.Marshal([]*Blog{testBlog()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*jsonapi.ManyPayload); !ok {
+		t.Fatalf("Expected a ManyPayload without SingletonAsObject, got %T", p)
+	}
+}
+
+func TestMarshalWithOptions_SortIncluded(t *testing.T) {
+	p, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{SortIncluded: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for _, n := range p.(*jsonapi.OnePayload).Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	assert.Equal(t, []string{"comments,1", "comments,2", "comments,3", "posts,1", "posts,2"}, keys)
+}
+
+func TestMarshalWithOptions_AllowedTypes(t *testing.T) {
+	opts := jsonapi.MarshalOptions{AllowedTypes: map[string]bool{"blogs": true, "posts": true, "comments": true}}
+	if _, err := jsonapi.MarshalWithOptions(testBlog(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	opts = jsonapi.MarshalOptions{AllowedTypes: map[string]bool{"blogs": true, "posts": true}}
+	_, err := jsonapi.MarshalWithOptions(testBlog(), opts)
+	assert.True(t, errors.Is(err, jsonapi.ErrDisallowedType), "expected ErrDisallowedType, got %v", err)
+}
+
+func TestMarshalValidate(t *testing.T) {
+	p, err := jsonapi.Marshal(testBlog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, jsonapi.MarshalValidate(p))
+
+	invalid := map[string]jsonapi.Payloader{
+		"document links": &jsonapi.ManyPayload{
+			Data:  []*jsonapi.ResourceObj{{Type: "posts", ID: "1"}},
+			Links: &jsonapi.Links{"next": 2},
+		},
+		"resource links": &jsonapi.OnePayload{
+			Data: &jsonapi.ResourceObj{Type: "posts", ID: "1", Links: &jsonapi.Links{"self": []string{"a", "b"}}},
+		},
+		"relationship links": &jsonapi.OnePayload{
+			Data: &jsonapi.ResourceObj{Type: "posts", ID: "1", Relationships: map[string]interface{}{
+				"author": &jsonapi.RelationshipOneNode{Links: &jsonapi.Links{"related": 42}},
+			}},
+		},
+		"missing type": &jsonapi.OnePayload{
+			Included: []*jsonapi.ResourceObj{{ID: "1"}},
+		},
+		"unencodable attribute": &jsonapi.OnePayload{
+			Data: &jsonapi.ResourceObj{Type: "posts", ID: "1", Attributes: map[string]interface{}{"ch": make(chan int)}},
+		},
+	}
+
+	for name, p := range invalid {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, jsonapi.MarshalValidate(p))
+		})
+	}
+}
+
+func TestCompactDocument(t *testing.T) {
+	p, err := jsonapi.Marshal(testBlog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonapi.CompactDocument(p)
+
+	one := p.(*jsonapi.OnePayload)
+	assert.Empty(t, one.Included)
+
+	posts := one.Data.Relationships["posts"].(*jsonapi.RelationshipManyNode)
+	assert.Len(t, posts.Data, 2)
+	assert.Nil(t, posts.Links)
+	assert.Nil(t, posts.Meta)
+
+	current := one.Data.Relationships["current_post"].(*jsonapi.RelationshipOneNode)
+	assert.Equal(t, &jsonapi.ResourceObj{Type: "posts", ID: "1"}, current.Data)
+	assert.Nil(t, current.Links)
+	assert.Nil(t, current.Meta)
+
+	many := &jsonapi.ManyPayload{
+		Data: []*jsonapi.ResourceObj{{Type: "posts", ID: "1", Relationships: map[string]interface{}{
+			"author": map[string]interface{}{
+				"data":  map[string]interface{}{"type": "people", "id": "9"},
+				"links": map[string]interface{}{"related": "/posts/1/author"},
+			},
+			"comments": &jsonapi.RelationshipMetaNode{Meta: &jsonapi.Meta{"count": 3}},
+		}}},
+		Included: []*jsonapi.ResourceObj{{Type: "people", ID: "9"}},
+	}
+	jsonapi.CompactDocument(many)
+
+	assert.Empty(t, many.Included)
+	assert.Equal(t, map[string]interface{}{
+		"author": &jsonapi.RelationshipOneNode{Data: &jsonapi.ResourceObj{Type: "people", ID: "9"}},
+	}, many.Data[0].Relationships)
+}
+
+func TestMarshal_NestedResourceAttribute(t *testing.T) {
+	_, err := jsonapi.Marshal(&Embedding{ID: "1", Doc: &jsonapi.ResourceObj{Type: "people", ID: "9"}})
+	assert.True(t, errors.Is(err, jsonapi.ErrNestedResource), "expected ErrNestedResource, got %v", err)
+	assert.Contains(t, err.Error(), "doc")
+
+	_, err = jsonapi.Marshal(&Embedding{ID: "1"})
+	assert.NoError(t, err)
+
+	p := &jsonapi.OnePayload{Data: &jsonapi.ResourceObj{Type: "posts", ID: "1", Attributes: map[string]interface{}{
+		"extra": []interface{}{"a", map[string]interface{}{"author": &jsonapi.ResourceObj{Type: "people", ID: "9"}}},
+	}}}
+	assert.True(t, errors.Is(jsonapi.MarshalValidate(p), jsonapi.ErrNestedResource))
+}
+
+func TestMarshal_ReservedAttribute(t *testing.T) {
+	_, err := jsonapi.Marshal(&ReservedAttribute{ID: "1", Kind: "kind"})
+	assert.True(t, errors.Is(err, jsonapi.ErrReservedAttribute), "expected ErrReservedAttribute, got %v", err)
+	assert.EqualError(t, err, "attributes must not be named type or id: type")
+
+	for _, name := range []string{"type", "id"} {
+		p := &jsonapi.ManyPayload{
+			Data:     []*jsonapi.ResourceObj{{Type: "posts", ID: "1"}},
+			Included: []*jsonapi.ResourceObj{{Type: "comments", ID: "1", Attributes: map[string]interface{}{name: "x"}}},
+		}
+		assert.True(t, errors.Is(jsonapi.MarshalValidate(p), jsonapi.ErrReservedAttribute), "the %s attribute is rejected", name)
+	}
+}
+
+func TestMarshalPayloadWithOptions_EmitEmptyMeta(t *testing.T) {
+	for _, models := range []interface{}{&Comment{ID: 1}, []*Comment{{ID: 1}}} {
+		out := bytes.NewBuffer(nil)
+		assert.NoError(t, jsonapi.MarshalPayloadWithOptions(out, models, jsonapi.MarshalOptions{EmitEmptyMeta: true}))
+		assert.Contains(t, out.String(), `"meta":{}`)
+
+		out.Reset()
+		assert.NoError(t, jsonapi.MarshalPayload(out, models))
+		assert.NotContains(t, out.String(), `"meta"`)
+	}
+
+	p, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{EmitEmptyMeta: true, MaxIncluded: 1, TruncateIncluded: true})
+	assert.NoError(t, err)
+	assert.Contains(t, *p.(*jsonapi.OnePayload).Meta, "warning", "existing meta is kept")
+}
+
+func TestMarshal_EmbeddedStructs(t *testing.T) {
+	created := time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)
+
+	p, err := jsonapi.Marshal(&Article{
+		Timestamps: Timestamps{CreatedAt: created, Label: "timestamps"},
+		Named:      &Named{Name: "Embedding", Label: "named"},
+		ID:         "1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"created_at": "2016-08-17T08:27:12Z",
+		"name":       "Embedding",
+	}, p.(*jsonapi.OnePayload).Data.Attributes, "the omitted label of the model wins over the embedded ones")
+
+	p, err = jsonapi.Marshal(&Article{Timestamps: Timestamps{CreatedAt: created}, ID: "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"created_at": "2016-08-17T08:27:12Z"}, p.(*jsonapi.OnePayload).Data.Attributes)
+
+	p, err = jsonapi.Marshal(&Tagged{
+		Timestamps: Timestamps{CreatedAt: created, Label: "timestamps"},
+		Named:      Named{Name: "Embedding", Label: "named"},
+		ID:         "1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"created_at": "2016-08-17T08:27:12Z",
+		"name":       "Embedding",
+	}, p.(*jsonapi.OnePayload).Data.Attributes, "a label declared by two embedded structs at the same depth is dropped")
+
+	layered := &Layered{Stamped: Stamped{Timestamps{CreatedAt: created, Label: "timestamps"}}, ID: "1"}
+	p, err = jsonapi.Marshal(layered)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"created_at": "2016-08-17T08:27:12Z",
+	}, p.(*jsonapi.OnePayload).Data.Attributes, "the omitted shallower label wins over the deeper one")
+
+	layered.Label = "labelled"
+	p, err = jsonapi.Marshal(layered)
+	assert.NoError(t, err)
+	assert.Equal(t, "labelled", p.(*jsonapi.OnePayload).Data.Attributes["label"])
+
+	p, err = jsonapi.Marshal(testBlog().Posts[0])
+	assert.NoError(t, err)
+	assert.NotContains(t, p.(*jsonapi.OnePayload).Data.Relationships, "current_post", "an embedded resource is not a base struct")
+}
+
+func TestMarshal_EmbeddedStructsShadowedRelationship(t *testing.T) {
+	p, err := jsonapi.Marshal([]*Review{{
+		Reviewed: Reviewed{Reviewer: &Comment{ID: 9}, Note: &Comment{ID: 8}},
+		ID:       "1",
+		Reviewer: &Comment{ID: 7},
+	}})
+	assert.NoError(t, err)
+	many := p.(*jsonapi.ManyPayload)
+
+	assert.Equal(t, "7", many.Data[0].Relationships["reviewer"].(*jsonapi.RelationshipOneNode).Data.ID)
+	assert.Equal(t, "8", many.Data[0].Relationships["note"].(*jsonapi.RelationshipOneNode).Data.ID)
+
+	keys := []string{}
+	for _, n := range many.Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	assert.ElementsMatch(t, []string{"comments,7", "comments,8"}, keys, "the shadowed reviewer is not included")
+	assert.NoError(t, jsonapi.ValidateFullLinkage(many))
+}
+
+func TestMarshal_LinkableResourceLinks(t *testing.T) {
+	p, err := jsonapi.Marshal(&Blog{ID: 7, Title: "Linked"})
+	assert.NoError(t, err)
+	links := p.(*jsonapi.OnePayload).Data.Links
+	if assert.NotNil(t, links) {
+		assert.Equal(t, "https://example.com/api/blogs/7", (*links)["self"])
+	}
+
+	p, err = jsonapi.Marshal([]*Blog{{ID: 1}, {ID: 2}})
+	assert.NoError(t, err)
+	for _, n := range p.(*jsonapi.ManyPayload).Data {
+		if assert.NotNil(t, n.Links) {
+			assert.Equal(t, "https://example.com/api/blogs/"+n.ID, (*n.Links)["self"], "each resource computes its own self link")
+		}
+	}
+
+	_, err = jsonapi.Marshal(&BadComment{ID: 1})
+	assert.Error(t, err, "invalid resource links are rejected")
+}
+
+func TestMarshal_RelationshipMetable(t *testing.T) {
+	p, err := jsonapi.Marshal(testBlog())
+	assert.NoError(t, err)
+	relationships := p.(*jsonapi.OnePayload).Data.Relationships
+
+	currentPost := relationships["current_post"].(*jsonapi.RelationshipOneNode)
+	assert.Equal(t, &jsonapi.Meta{"detail": "extra current_post detail"}, currentPost.Meta)
+
+	posts := relationships["posts"].(*jsonapi.RelationshipManyNode)
+	if assert.NotNil(t, posts.Meta) {
+		assert.Contains(t, *posts.Meta, "this")
+	}
+
+	p, err = jsonapi.Marshal(&Blog{ID: 1})
+	assert.NoError(t, err)
+	currentPost = p.(*jsonapi.OnePayload).Data.Relationships["current_post"].(*jsonapi.RelationshipOneNode)
+	assert.Nil(t, currentPost.Data)
+	assert.Equal(t, &jsonapi.Meta{"detail": "extra current_post detail"}, currentPost.Meta, "a null relationship keeps its meta")
+	if assert.NotNil(t, currentPost.Links) {
+		assert.Contains(t, *currentPost.Links, "related", "and its links")
+	}
+}
+
+func TestMarshalPayloadWithOptions_EmitEmptyRelationships(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	assert.NoError(t, jsonapi.MarshalPayloadWithOptions(out, &Comment{ID: 1, Body: "Empty"}, jsonapi.MarshalOptions{EmitEmptyRelationships: true}))
+	assert.JSONEq(t, `{"data": {"type": "comments", "id": "1", "attributes": {"post_id": 0, "body": "Empty"}, "relationships": {}}}`, out.String())
+
+	out.Reset()
+	assert.NoError(t, jsonapi.MarshalPayload(out, &Comment{ID: 1, Body: "Empty"}))
+	assert.NotContains(t, out.String(), "relationships", "the default omits them")
+
+	out.Reset()
+	assert.NoError(t, jsonapi.MarshalPayloadWithOptions(out, &Post{ID: 1, LatestComment: &Comment{ID: 2}}, jsonapi.MarshalOptions{EmitEmptyRelationships: true}))
+	var doc struct {
+		Data     json.RawMessage   `json:"data"`
+		Included []json.RawMessage `json:"included"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Contains(t, string(doc.Data), `"latest_comment"`, "populated relationships are unchanged")
+	if assert.Len(t, doc.Included, 1) {
+		assert.Contains(t, string(doc.Included[0]), `"relationships":{}`, "included resources are forced too")
+	}
+}
+
+func TestMarshal_MergesIncludedMeta(t *testing.T) {
+	lead, member, roster := "lead", "member", "roster"
+	squad := &Squad{
+		ID:      "1",
+		Lead:    &Member{ID: "7", Role: &lead},
+		Members: []*Member{{ID: "7", Role: &member, Source: &roster}},
+	}
+
+	for laterWins, expected := range map[bool]string{
+		false: `{"role": "lead", "source": "roster"}`,
+		true:  `{"role": "member", "source": "roster"}`,
+	} {
+		p, err := jsonapi.MarshalWithOptions(squad, jsonapi.MarshalOptions{LaterIncludedMetaWins: laterWins})
+		assert.NoError(t, err)
+
+		included := p.(*jsonapi.OnePayload).Included
+		if assert.Len(t, included, 1) {
+			meta, err := json.Marshal(included[0].Meta)
+			assert.NoError(t, err)
+			assert.JSONEq(t, expected, string(meta), "LaterIncludedMetaWins: %t", laterWins)
+		}
+	}
+}
+
+func TestMarshal_BigNumberAttributes(t *testing.T) {
+	balance, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.True(t, ok)
+	rate, ok := new(big.Float).SetPrec(200).SetString("0.000000000000000000012345")
+	assert.True(t, ok)
+
+	out := bytes.NewBuffer(nil)
+	assert.NoError(t, jsonapi.MarshalPayload(out, &Ledger{ID: "1", Balance: balance, Rate: rate}))
+	assert.JSONEq(t, `{"data": {"type": "ledgers", "id": "1", "attributes": {
+		"balance": "123456789012345678901234567890",
+		"rate": "0.000000000000000000012345"
+	}}}`, out.String())
+
+	out.Reset()
+	assert.NoError(t, jsonapi.MarshalPayload(out, &Ledger{ID: "2"}))
+	assert.JSONEq(t, `{"data": {"type": "ledgers", "id": "2", "attributes": {"balance": null}}}`, out.String())
+}
+
+func TestResourceObj_MarshalJSON_BigNumberAttributes(t *testing.T) {
+	balance, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.True(t, ok)
+
+	resource := &jsonapi.ResourceObj{Type: "ledgers", ID: "1", Attributes: map[string]interface{}{
+		"balance": balance,
+		"rate":    *big.NewFloat(0.5),
+		"name":    "savings",
+	}}
+	out, err := json.Marshal(&jsonapi.OnePayload{Data: resource})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data": {"type": "ledgers", "id": "1", "attributes": {
+		"balance": "123456789012345678901234567890",
+		"rate": "0.5",
+		"name": "savings"
+	}}}`, string(out))
+	assert.Equal(t, balance, resource.Attributes["balance"], "the attributes of the resource are left as they are")
+}
+
+func TestMarshalWithOptions_MaxLinkage(t *testing.T) {
+	p, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{MaxLinkage: 2, SortIncluded: true})
+	assert.NoError(t, err)
+	one := p.(*jsonapi.OnePayload)
+	assert.Len(t, one.Data.Relationships["posts"].(*jsonapi.RelationshipManyNode).Data, 2, "at the threshold the linkage is kept")
+	assert.Len(t, one.Included, 5)
+
+	p, err = jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{MaxLinkage: 1, SortIncluded: true})
+	assert.NoError(t, err)
+	one = p.(*jsonapi.OnePayload)
+
+	posts, ok := one.Data.Relationships["posts"].(*jsonapi.RelationshipMetaNode)
+	if assert.True(t, ok, "over the threshold only links and meta are kept") {
+		assert.Contains(t, *posts.Links, "related")
+		assert.Contains(t, *posts.Meta, "this")
+	}
+
+	keys := []string{}
+	for _, n := range one.Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	assert.Equal(t, []string{"comments,1", "comments,2", "posts,1"}, keys, "only the current_post is included")
+
+	current := fullNodeByKey(one.Included, "posts,1")
+	assert.Len(t, current.Relationships["comments"].(*jsonapi.RelationshipManyNode).Data, 2, "without relationship links the linkage is kept")
+	assert.NoError(t, jsonapi.MarshalValidate(p))
+}
+
+func TestMarshalWithOptions_MaxLinkageRelationshipLinks(t *testing.T) {
+	p, err := jsonapi.MarshalWithOptions(testBlog(), jsonapi.MarshalOptions{
+		MaxLinkage:        1,
+		SortIncluded:      true,
+		RelationshipLinks: &jsonapi.RelationshipLinkOptions{BaseURL: "https://example.com"},
+	})
+	assert.NoError(t, err)
+	one := p.(*jsonapi.OnePayload)
+
+	current := fullNodeByKey(one.Included, "posts,1")
+	if assert.NotNil(t, current) {
+		comments, ok := current.Relationships["comments"].(*jsonapi.RelationshipMetaNode)
+		if assert.True(t, ok, "generated relationship links replace the linkage") {
+			assert.Equal(t, "https://example.com/posts/1/relationships/comments", (*comments.Links)["self"])
+			assert.Equal(t, "https://example.com/posts/1/comments", (*comments.Links)["related"])
+		}
+	}
+
+	keys := []string{}
+	for _, n := range one.Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	assert.Equal(t, []string{"comments,1", "posts,1"}, keys, "only the latest_comment of the current_post is included")
+	assert.NoError(t, jsonapi.MarshalValidate(p))
+}
+
+func TestMarshalWithOptions_MaxLinkageRelationshipLinksBeforePrimary(t *testing.T) {
+	p, err := jsonapi.MarshalWithOptions(&Shelf{Books: []*Book{{ID: 1}, {ID: 2}}, ID: "1"}, jsonapi.MarshalOptions{
+		MaxLinkage:        1,
+		RelationshipLinks: &jsonapi.RelationshipLinkOptions{BaseURL: "https://example.com"},
+	})
+	assert.NoError(t, err)
+	one := p.(*jsonapi.OnePayload)
+
+	books, ok := one.Data.Relationships["books"].(*jsonapi.RelationshipMetaNode)
+	if assert.True(t, ok, "the relation declared before the primary field is left to its links") {
+		assert.Equal(t, "https://example.com/shelves/1/relationships/books", (*books.Links)["self"])
+	}
+	assert.Empty(t, one.Included)
+	assert.NoError(t, jsonapi.MarshalValidate(p))
+}
+
+func fullNodeByKey(nodes []*jsonapi.ResourceObj, key string) *jsonapi.ResourceObj {
+	for _, n := range nodes {
+		if n.Type+","+n.ID == key {
+			return n
+		}
+	}
+	return nil
+}
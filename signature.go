@@ -0,0 +1,73 @@
+package jsonapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// VerifyPageSignature reports whether the sig query parameter of the link
+// rawURL is the signature of its page parameters keyed by secret, as added
+// by the SigningSecret of OffsetPagination. A link without a signature, or
+// whose page parameters were changed since it was signed, does not verify.
+func VerifyPageSignature(rawURL string, secret []byte) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	query := u.Query()
+	sig, err := base64.RawURLEncoding.DecodeString(query.Get(QueryParamSignature))
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+
+	return hmac.Equal(sig, pageSignature(query, secret))
+}
+
+// signPageLink returns link with its sig query parameter set to the signature
+// of its page parameters, replacing any sig it had, e.g. when copied from a
+// signed request URL. The rest of the query is kept as it was encoded. Given
+// no secret, link is returned as is.
+func signPageLink(link string, secret []byte) string {
+	if len(secret) == 0 {
+		return link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	var params []string
+	if u.RawQuery != "" {
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			if param != QueryParamSignature && !strings.HasPrefix(param, QueryParamSignature+"=") {
+				params = append(params, param)
+			}
+		}
+	}
+
+	sig := base64.RawURLEncoding.EncodeToString(pageSignature(u.Query(), secret))
+	u.RawQuery = strings.Join(append(params, QueryParamSignature+"="+sig), "&")
+
+	return u.String()
+}
+
+// pageSignature returns the HMAC-SHA256, keyed by secret, of the page
+// parameters of query: the page family, e.g. page[offset], and the scalar
+// page and per_page parameters, encoded sorted by key.
+func pageSignature(query url.Values, secret []byte) []byte {
+	params := url.Values{}
+	for key, values := range query {
+		if key == QueryParamPage || key == QueryParamPerPage || strings.HasPrefix(key, QueryParamPage+"[") {
+			params[key] = values
+		}
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(params.Encode()))
+	return mac.Sum(nil)
+}
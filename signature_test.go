@@ -0,0 +1,41 @@
+package jsonapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestVerifyPageSignature(t *testing.T) {
+	secret := []byte("s3cr3t")
+	p := &jsonapi.OffsetPagination{
+		URL:           "https://example.com/articles?filter[tag]=go&page[limit]=100&page[offset]=100&sig=stale",
+		Limit:         100,
+		Total:         334,
+		EmitSelfLink:  true,
+		SigningSecret: secret,
+	}
+
+	links := p.GeneratePagination()
+	for _, key := range []string{"self", "first", "prev", "next", "last"} {
+		link := (*links)[key].(string)
+		assert.True(t, jsonapi.VerifyPageSignature(link, secret), key)
+		assert.Equal(t, 1, strings.Count(link, "sig="), key)
+		assert.Contains(t, link, "filter[tag]=go", key)
+	}
+
+	next := (*links)["next"].(string)
+	assert.False(t, jsonapi.VerifyPageSignature(next, []byte("other")))
+	assert.False(t, jsonapi.VerifyPageSignature(strings.Replace(next, "page[offset]=200", "page[offset]=0", 1), secret))
+	assert.False(t, jsonapi.VerifyPageSignature(strings.Replace(next, "page[limit]=100", "page[limit]=1000", 1), secret))
+	assert.False(t, jsonapi.VerifyPageSignature("https://example.com/articles?page[limit]=100&page[offset]=200", secret))
+
+	// Parameters outside the page family are not signed.
+	assert.True(t, jsonapi.VerifyPageSignature(strings.Replace(next, "filter[tag]=go", "filter[tag]=rust", 1), secret))
+
+	unsigned := (&jsonapi.OffsetPagination{URL: "/articles", Limit: 100, Total: 334}).GeneratePagination()
+	assert.NotContains(t, (*unsigned)["next"], "sig=")
+}
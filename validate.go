@@ -0,0 +1,357 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidateLinkage checks that every resource identifier in the relationships
+// of o has both a type and an id, as required of resource linkage. A resource
+// object embedded in a relationship, with attributes or relationships of its
+// own as written by MarshalOnePayloadEmbedded, may be new and so only needs a
+// type. Unmarshaling a payload into a model checks its resources with it.
+//
+// http://jsonapi.org/format/#document-resource-object-linkage
+func ValidateLinkage(o *ResourceObj) error {
+	if o == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(o.Relationships))
+	for name := range o.Relationships {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, n := range relationshipLinkage(o.Relationships[name]) {
+			if n == nil {
+				return fmt.Errorf("The %s relationship contains a null resource identifier", name)
+			}
+			if n.Type == "" {
+				return fmt.Errorf("The %s relationship contains a resource identifier without a type", name)
+			}
+			if n.ID == "" && len(n.Attributes) == 0 && len(n.Relationships) == 0 {
+				return fmt.Errorf("The %s relationship contains a resource identifier without an id", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateLinkageType checks that every resource identifier of objs, e.g. as
+// returned by UnmarshalLinkage, has the type expected of the relationship,
+// e.g. "comments" for a comments relationship.
+func ValidateLinkageType(objs []*ResourceObj, expected string) error {
+	for i, n := range objs {
+		if n == nil {
+			return fmt.Errorf("The resource identifier at data[%d] is null", i)
+		}
+		if n.Type != expected {
+			return fmt.Errorf("The resource identifier at data[%d] has type %s, expected %s", i, n.Type, expected)
+		}
+	}
+	return nil
+}
+
+// ValidateFullLinkage checks that every resource in the "included" array of p
+// is referenced by a relationship of the primary data or of another included
+// resource, as full linkage requires, and lists those that are not.
+//
+// http://jsonapi.org/format/#document-compound-documents
+func ValidateFullLinkage(p *ManyPayload) error {
+	referenced := make(map[string]bool)
+	reference := func(n *ResourceObj) {
+		for _, relationship := range n.Relationships {
+			for _, linkage := range relationshipLinkage(relationship) {
+				if key := resourceKey(linkage); key != resourceKey(n) {
+					referenced[key] = true
+				}
+			}
+		}
+	}
+
+	for _, n := range p.Data {
+		if n != nil {
+			reference(n)
+		}
+	}
+	for _, n := range p.Included {
+		if n != nil {
+			reference(n)
+		}
+	}
+
+	var orphans []string
+	for _, n := range p.Included {
+		if n != nil && !referenced[resourceKey(n)] {
+			orphans = append(orphans, resourceKey(n))
+		}
+	}
+	if len(orphans) > 0 {
+		return fmt.Errorf("The included resources %s are not referenced by any relationship", strings.Join(orphans, "; "))
+	}
+
+	return nil
+}
+
+// ValidateDocument checks the structure of the raw JSON API document raw:
+// the members of the top level, resource objects and their relationships,
+// errors, links and meta. Unlike ValidateLinkage it works on the decoded
+// document, not on the types of this package, so it also catches documents
+// that those types would silently accept.
+//
+// http://jsonapi.org/format/#document-structure
+func ValidateDocument(raw []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+
+	top, ok := doc.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("The document must be an object")
+	}
+
+	_, hasData := top["data"]
+	_, hasErrors := top["errors"]
+	_, hasMeta := top["meta"]
+	_, hasIncluded := top["included"]
+
+	switch {
+	case !hasData && !hasErrors && !hasMeta:
+		return fmt.Errorf("The document must contain at least one of data, errors or meta")
+	case hasData && hasErrors:
+		return fmt.Errorf("The document must not contain both data and errors")
+	case hasIncluded && !hasData:
+		return fmt.Errorf("The document must not contain included without data")
+	}
+
+	if hasData {
+		switch data := top["data"].(type) {
+		case nil:
+		case map[string]interface{}:
+			if err := validateResource("data", data, false); err != nil {
+				return err
+			}
+		case []interface{}:
+			for i, v := range data {
+				if err := validateResource(fmt.Sprintf("data[%d]", i), v, true); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("The data member must be null, an object or an array")
+		}
+	}
+
+	if hasIncluded {
+		included, ok := top["included"].([]interface{})
+		if !ok {
+			return fmt.Errorf("The included member must be an array")
+		}
+		for i, v := range included {
+			if err := validateResource(fmt.Sprintf("included[%d]", i), v, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	if hasErrors {
+		errs, ok := top["errors"].([]interface{})
+		if !ok {
+			return fmt.Errorf("The errors member must be an array")
+		}
+		for i, v := range errs {
+			if _, ok := v.(map[string]interface{}); !ok {
+				return fmt.Errorf("The error at errors[%d] must be an object", i)
+			}
+		}
+	}
+
+	return validateMembers("document", top)
+}
+
+// validateResource checks the resource object v found at path. Resources in
+// the primary data of a create request may omit their id, so an id is only
+// required when requireID is set.
+func validateResource(path string, v interface{}, requireID bool) error {
+	resource, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("The resource at %s must be an object", path)
+	}
+
+	if typ, ok := resource["type"].(string); !ok || typ == "" {
+		return fmt.Errorf("The resource at %s must have a type", path)
+	}
+	if id, ok := resource["id"]; ok {
+		if _, ok := id.(string); !ok {
+			return fmt.Errorf("The resource at %s must have a string id", path)
+		}
+	} else if requireID {
+		return fmt.Errorf("The resource at %s must have an id", path)
+	}
+
+	if attributes, ok := resource["attributes"]; ok {
+		if _, ok := attributes.(map[string]interface{}); !ok {
+			return fmt.Errorf("The attributes of the resource at %s must be an object", path)
+		}
+	}
+
+	if relationships, ok := resource["relationships"]; ok {
+		rels, ok := relationships.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("The relationships of the resource at %s must be an object", path)
+		}
+
+		names := make([]string, 0, len(rels))
+		for name := range rels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if err := validateRelationship(path, name, rels[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return validateMembers("resource at "+path, resource)
+}
+
+func validateRelationship(path, name string, v interface{}) error {
+	rel, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("The %s relationship of the resource at %s must be an object", name, path)
+	}
+
+	data, hasData := rel["data"]
+	_, hasLinks := rel["links"]
+	_, hasMeta := rel["meta"]
+	if !hasData && !hasLinks && !hasMeta {
+		return fmt.Errorf("The %s relationship of the resource at %s must contain at least one of data, links or meta", name, path)
+	}
+
+	var identifiers []interface{}
+	switch linkage := data.(type) {
+	case nil:
+	case map[string]interface{}:
+		identifiers = []interface{}{linkage}
+	case []interface{}:
+		identifiers = linkage
+	default:
+		return fmt.Errorf("The %s relationship of the resource at %s must have null, an object or an array as data", name, path)
+	}
+
+	for _, identifier := range identifiers {
+		i, ok := identifier.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("The %s relationship of the resource at %s contains a resource identifier that is not an object", name, path)
+		}
+		if typ, ok := i["type"].(string); !ok || typ == "" {
+			return fmt.Errorf("The %s relationship of the resource at %s contains a resource identifier without a type", name, path)
+		}
+		if id, ok := i["id"].(string); !ok || id == "" {
+			return fmt.Errorf("The %s relationship of the resource at %s contains a resource identifier without an id", name, path)
+		}
+	}
+
+	return validateMembers(fmt.Sprintf("%s relationship of the resource at %s", name, path), rel)
+}
+
+// validateMembers checks that the links and meta members of object, if
+// present, are objects.
+func validateMembers(what string, object map[string]interface{}) error {
+	for _, member := range []string{"links", "meta"} {
+		value, ok := object[member]
+		if !ok {
+			continue
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("The %s of the %s must be an object", member, what)
+		}
+	}
+	return nil
+}
+
+var (
+	documentMembers = map[string]bool{
+		"data": true, "errors": true, "meta": true, "links": true, "included": true, "jsonapi": true,
+	}
+	resourceMembers = map[string]bool{
+		"type": true, "id": true, "lid": true, "attributes": true, "relationships": true, "links": true, "meta": true,
+	}
+)
+
+// checkUnknownMembers checks that the raw document, and the resource objects
+// of its "data" and "included", have no members other than those of the JSON
+// API specification, for strict decoding.
+func checkUnknownMembers(raw []byte) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	if err := checkMembers("document", doc, documentMembers); err != nil {
+		return err
+	}
+
+	if data := bytes.TrimSpace(doc["data"]); len(data) > 0 && data[0] == '{' {
+		if err := checkResourceMembers("data", data); err != nil {
+			return err
+		}
+	} else if len(data) > 0 && data[0] == '[' {
+		var resources []json.RawMessage
+		if err := json.Unmarshal(data, &resources); err != nil {
+			return err
+		}
+		for i, resource := range resources {
+			if err := checkResourceMembers(fmt.Sprintf("data[%d]", i), resource); err != nil {
+				return err
+			}
+		}
+	}
+
+	var included []json.RawMessage
+	if len(doc["included"]) > 0 {
+		if err := json.Unmarshal(doc["included"], &included); err != nil {
+			return err
+		}
+	}
+	for i, resource := range included {
+		if err := checkResourceMembers(fmt.Sprintf("included[%d]", i), resource); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkResourceMembers(path string, raw json.RawMessage) error {
+	var resource map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &resource); err != nil {
+		return err
+	}
+	return checkMembers("resource at "+path, resource, resourceMembers)
+}
+
+// checkMembers returns an error for the first, in name order, of the members
+// of object that is not known.
+func checkMembers(what string, object map[string]json.RawMessage, known map[string]bool) error {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("The %s has an unknown member %s", what, name)
+		}
+	}
+	return nil
+}
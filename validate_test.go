@@ -0,0 +1,197 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLinkage(t *testing.T) {
+	var tests = map[string]struct {
+		resource string
+		err      string
+	}{
+		"valid identifiers": {
+			resource: `{"type": "posts", "id": "1", "relationships": {
+				"author": {"data": {"type": "people", "id": "9"}},
+				"comments": {"data": [{"type": "comments", "id": "5"}, {"type": "comments", "id": "12"}]}
+			}}`,
+		},
+		"null and empty linkage": {
+			resource: `{"type": "posts", "id": "1", "relationships": {
+				"author": {"data": null},
+				"comments": {"data": []}
+			}}`,
+		},
+		"missing type": {
+			resource: `{"type": "posts", "id": "1", "relationships": {
+				"author": {"data": {"id": "9"}}
+			}}`,
+			err: "The author relationship contains a resource identifier without a type",
+		},
+		"missing id": {
+			resource: `{"type": "posts", "id": "1", "relationships": {
+				"comments": {"data": [{"type": "comments", "id": "5"}, {"type": "comments"}]}
+			}}`,
+			err: "The comments relationship contains a resource identifier without an id",
+		},
+		"embedded new resource": {
+			resource: `{"type": "posts", "relationships": {
+				"author": {"data": {"type": "people", "attributes": {"name": "New"}}}
+			}}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resource := new(ResourceObj)
+			if err := json.Unmarshal([]byte(test.resource), resource); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateLinkage(resource)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestValidateLinkage_RelationshipNodes(t *testing.T) {
+	resource := &ResourceObj{
+		Type: "posts",
+		ID:   "1",
+		Relationships: map[string]interface{}{
+			"author": &RelationshipOneNode{Data: &ResourceObj{Type: "people"}},
+		},
+	}
+
+	assert.EqualError(t, ValidateLinkage(resource), "The author relationship contains a resource identifier without an id")
+}
+
+func TestValidateLinkageType(t *testing.T) {
+	comments := []*ResourceObj{{Type: "comments", ID: "5"}, {Type: "comments", ID: "12"}}
+	assert.NoError(t, ValidateLinkageType(comments, "comments"))
+	assert.NoError(t, ValidateLinkageType([]*ResourceObj{}, "comments"))
+
+	mixed := []*ResourceObj{{Type: "comments", ID: "5"}, {Type: "people", ID: "9"}}
+	assert.EqualError(t, ValidateLinkageType(mixed, "comments"),
+		"The resource identifier at data[1] has type people, expected comments")
+	assert.EqualError(t, ValidateLinkageType([]*ResourceObj{nil}, "comments"),
+		"The resource identifier at data[0] is null")
+}
+
+func TestValidateFullLinkage(t *testing.T) {
+	p := &ManyPayload{
+		Data: []*ResourceObj{{Type: "posts", ID: "1", Relationships: map[string]interface{}{
+			"author": NewRelationshipOne("people", "9"),
+		}}},
+		Included: []*ResourceObj{
+			{Type: "people", ID: "9", Relationships: map[string]interface{}{
+				"employer": NewRelationshipOne("companies", "2"),
+			}},
+			{Type: "companies", ID: "2"},
+		},
+	}
+	assert.NoError(t, ValidateFullLinkage(p))
+
+	p.Included = append(p.Included,
+		&ResourceObj{Type: "comments", ID: "3", Relationships: map[string]interface{}{
+			"self": NewRelationshipOne("comments", "3"),
+		}},
+		&ResourceObj{Type: "tags", ID: "4"},
+	)
+	assert.EqualError(t, ValidateFullLinkage(p),
+		"The included resources comments,3; tags,4 are not referenced by any relationship")
+}
+
+func TestValidateDocument(t *testing.T) {
+	var tests = map[string]struct {
+		document string
+		err      string
+	}{
+		"valid compound document": {
+			document: `{
+				"data": [{"type": "posts", "id": "1", "attributes": {"title": "Hi"}, "relationships": {
+					"author": {"data": {"type": "people", "id": "9"}, "links": {"related": "/posts/1/author"}},
+					"comments": {"meta": {"count": 0}}
+				}}],
+				"included": [{"type": "people", "id": "9"}],
+				"links": {"self": "/posts"},
+				"meta": {"total": 1}
+			}`,
+		},
+		"create without id": {
+			document: `{"data": {"type": "posts", "attributes": {"title": "Hi"}}}`,
+		},
+		"errors only": {
+			document: `{"errors": [{"status": "404"}]}`,
+		},
+		"not an object": {
+			document: `[]`,
+			err:      "The document must be an object",
+		},
+		"no top-level member": {
+			document: `{"links": {"self": "/posts"}}`,
+			err:      "The document must contain at least one of data, errors or meta",
+		},
+		"data and errors": {
+			document: `{"data": null, "errors": []}`,
+			err:      "The document must not contain both data and errors",
+		},
+		"included without data": {
+			document: `{"meta": {}, "included": []}`,
+			err:      "The document must not contain included without data",
+		},
+		"resource without type": {
+			document: `{"data": {"id": "1"}}`,
+			err:      "The resource at data must have a type",
+		},
+		"included without id": {
+			document: `{"data": null, "included": [{"type": "people"}]}`,
+			err:      "The resource at included[0] must have an id",
+		},
+		"numeric id": {
+			document: `{"data": [{"type": "posts", "id": 1}]}`,
+			err:      "The resource at data[0] must have a string id",
+		},
+		"attributes not an object": {
+			document: `{"data": {"type": "posts", "attributes": []}}`,
+			err:      "The attributes of the resource at data must be an object",
+		},
+		"relationship not an object": {
+			document: `{"data": {"type": "posts", "relationships": {"author": "9"}}}`,
+			err:      "The author relationship of the resource at data must be an object",
+		},
+		"empty relationship": {
+			document: `{"data": {"type": "posts", "relationships": {"author": {}}}}`,
+			err:      "The author relationship of the resource at data must contain at least one of data, links or meta",
+		},
+		"identifier without id": {
+			document: `{"data": {"type": "posts", "relationships": {"tags": {"data": [{"type": "tags"}]}}}}`,
+			err:      "The tags relationship of the resource at data contains a resource identifier without an id",
+		},
+		"errors not an array": {
+			document: `{"errors": {"status": "404"}}`,
+			err:      "The errors member must be an array",
+		},
+		"meta not an object": {
+			document: `{"data": null, "meta": 1}`,
+			err:      "The meta of the document must be an object",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateDocument([]byte(test.document))
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package jsonapi
+
+import (
+	"errors"
+	"strings"
+)
+
+const metaKeyVersion = "version"
+
+// ErrVersionMismatch is returned by CheckResourceVersion when the version of
+// a resource is not one the client expected; servers respond with 412
+// Precondition Failed.
+var ErrVersionMismatch = errors.New("resource version does not match")
+
+// SetResourceVersion stores the version token of the resource o, e.g. a
+// revision number or a hash of its state, as "version" in its meta.
+func SetResourceVersion(o *ResourceObj, version string) {
+	if o.Meta == nil {
+		o.Meta = &Meta{}
+	}
+	(*o.Meta)[metaKeyVersion] = version
+}
+
+// GetResourceVersion returns the version token stored in the meta of the
+// resource o by SetResourceVersion.
+func GetResourceVersion(o *ResourceObj) (string, bool) {
+	if o.Meta == nil {
+		return "", false
+	}
+	version, ok := (*o.Meta)[metaKeyVersion].(string)
+	return version, ok
+}
+
+// CheckResourceVersion checks the version of o, for optimistic concurrency,
+// against ifMatch, the If-Match header of a PATCH request: a comma separated
+// list of versions, quoted or not and optionally weak (W/), or "*" for any
+// version. An empty ifMatch always matches, while o without a version only
+// matches "*".
+func CheckResourceVersion(o *ResourceObj, ifMatch string) error {
+	if strings.TrimSpace(ifMatch) == "" {
+		return nil
+	}
+
+	version, hasVersion := GetResourceVersion(o)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil
+		}
+		tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+		if hasVersion && tag == version {
+			return nil
+		}
+	}
+
+	return ErrVersionMismatch
+}
@@ -0,0 +1,42 @@
+package jsonapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elasticpath/jsonapi"
+)
+
+func TestResourceVersion(t *testing.T) {
+	o := &jsonapi.ResourceObj{Type: "articles", ID: "1", Meta: &jsonapi.Meta{"views": 3}}
+
+	_, ok := jsonapi.GetResourceVersion(o)
+	assert.False(t, ok)
+
+	jsonapi.SetResourceVersion(o, "v7")
+	version, ok := jsonapi.GetResourceVersion(o)
+	assert.True(t, ok)
+	assert.Equal(t, "v7", version)
+	assert.Equal(t, &jsonapi.Meta{"views": 3, "version": "v7"}, o.Meta)
+
+	empty := &jsonapi.ResourceObj{Type: "articles", ID: "2"}
+	jsonapi.SetResourceVersion(empty, "v1")
+	assert.Equal(t, &jsonapi.Meta{"version": "v1"}, empty.Meta)
+}
+
+func TestCheckResourceVersion(t *testing.T) {
+	o := &jsonapi.ResourceObj{Type: "articles", ID: "1"}
+	jsonapi.SetResourceVersion(o, "v7")
+
+	for _, ifMatch := range []string{"", "v7", `"v7"`, `W/"v7"`, `"v6", "v7"`, "*"} {
+		assert.NoError(t, jsonapi.CheckResourceVersion(o, ifMatch), ifMatch)
+	}
+	for _, ifMatch := range []string{"v6", `"v6", W/"v8"`} {
+		assert.Equal(t, jsonapi.ErrVersionMismatch, jsonapi.CheckResourceVersion(o, ifMatch), ifMatch)
+	}
+
+	unversioned := &jsonapi.ResourceObj{Type: "articles", ID: "2"}
+	assert.Equal(t, jsonapi.ErrVersionMismatch, jsonapi.CheckResourceVersion(unversioned, `""`))
+	assert.NoError(t, jsonapi.CheckResourceVersion(unversioned, "*"))
+}